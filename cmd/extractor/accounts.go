@@ -0,0 +1,179 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/Zaphkiel-Ivanovna/GoogleAuthExtractor/internal/decoder"
+	"github.com/Zaphkiel-Ivanovna/GoogleAuthExtractor/internal/input"
+	"github.com/Zaphkiel-Ivanovna/GoogleAuthExtractor/internal/logging"
+	"github.com/Zaphkiel-Ivanovna/GoogleAuthExtractor/internal/merge"
+	"github.com/Zaphkiel-Ivanovna/GoogleAuthExtractor/internal/output"
+)
+
+// stdin is shared by every prompt. A scanner reads ahead, so a second one
+// would lose the answers piped in for later prompts.
+var stdin = bufio.NewScanner(os.Stdin)
+
+// getAccounts decodes every input: each image given with --qrimage is a
+// source of its own, and so is each export given as URIs with --uri and the
+// arguments, whose batches can be passed separately.
+func getAccounts(args []string) ([]merge.Source, error) {
+	var sources []merge.Source
+
+	for _, path := range qrImagePaths {
+		results, err := input.ExtractQRCodes(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to extract QR code from image: %w", err)
+		}
+
+		var uris []string
+		for _, result := range results {
+			if result.Source != "" {
+				logging.Successf("Found QR code on %s (%s)", result.Source, result.Strategy)
+			} else {
+				logging.Successf("Successfully extracted QR code from image (%s)", result.Strategy)
+			}
+			uris = append(uris, result.Text)
+		}
+
+		source, err := decodeSource(path, uris)
+		if err != nil {
+			return nil, err
+		}
+		sources = append(sources, source)
+	}
+
+	uris := append(append([]string(nil), uriFlags...), args...)
+	if interactiveInput || (len(qrImagePaths) == 0 && len(uris) == 0) {
+		if extractedURI := promptURI(); extractedURI != "" {
+			uris = append(uris, extractedURI)
+		}
+	}
+
+	if len(uris) > 0 {
+		exports, err := decoder.DecodeExports(uris, decodeOptions())
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode URI: %w", err)
+		}
+		for _, export := range exports {
+			logging.Successf("Successfully decoded %d accounts", len(export.Accounts))
+			sources = append(sources, merge.Source{Name: fmt.Sprintf("URI export %d", export.BatchID), Accounts: export.Accounts})
+		}
+	}
+
+	if len(sources) == 0 {
+		return nil, fmt.Errorf("%w: no URI provided. Use --uri, --qrimage, or --interactive flags", errUsage)
+	}
+
+	return sources, nil
+}
+
+func decodeSource(name string, uris []string) (merge.Source, error) {
+	accounts, err := decoder.DecodeExportURIs(uris, decodeOptions())
+	if err != nil {
+		return merge.Source{}, fmt.Errorf("failed to decode URI: %w", err)
+	}
+
+	logging.Successf("Successfully decoded %d accounts", len(accounts))
+	return merge.Source{Name: name, Accounts: accounts}, nil
+}
+
+func decodeOptions() decoder.Options {
+	return decoder.Options{Strict: strictVersion, Hardened: hardenMemory}
+}
+
+func handleLegacyCommand(args []string) error {
+	accounts, err := loadAccounts(args)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(os.Stderr, "\nHow would you like to output the accounts?")
+	fmt.Fprintln(os.Stderr, "1. Save to JSON file")
+	fmt.Fprintln(os.Stderr, "2. Print JSON to terminal")
+	fmt.Fprintln(os.Stderr, "3. Generate QR code files")
+	fmt.Fprintln(os.Stderr, "4. Display in terminal")
+	fmt.Fprint(os.Stderr, "\nEnter option (1-4): ")
+
+	stdin.Scan()
+	choice := strings.TrimSpace(stdin.Text())
+
+	switch choice {
+	case "1":
+		fmt.Fprintf(os.Stderr, "Save to JSON file '%s'? [y/N]: ", jsonFile)
+		stdin.Scan()
+		if strings.HasPrefix(strings.ToLower(stdin.Text()), "y") {
+			err = output.SaveToJSON(accounts, resolveOutputPath(jsonFile))
+			if err != nil {
+				return fmt.Errorf("failed to save JSON: %w", err)
+			}
+		}
+	case "2":
+		err = output.PrintJSON(accounts)
+		if err != nil {
+			return err
+		}
+	case "3":
+		fmt.Fprintf(os.Stderr, "Save QR codes to directory '%s'? [y/N]: ", qrCodesDir)
+		stdin.Scan()
+		if strings.HasPrefix(strings.ToLower(stdin.Text()), "y") {
+			err = output.SaveToQRCodes(accounts, resolveOutputPath(qrCodesDir), qrImage)
+			if err != nil {
+				return fmt.Errorf("failed to generate QR codes: %w", err)
+			}
+		}
+	case "4":
+		fmt.Fprint(os.Stderr, "Use pretty formatting? [Y/n]: ")
+		stdin.Scan()
+		pretty := !strings.HasPrefix(strings.ToLower(stdin.Text()), "n")
+
+		fmt.Fprint(os.Stderr, "Show QR codes in terminal? [y/N]: ")
+		stdin.Scan()
+		showQR := strings.HasPrefix(strings.ToLower(stdin.Text()), "y")
+
+		fmt.Fprint(os.Stderr, "Show full secrets? (CAUTION: Secrets will be visible) [y/N]: ")
+		stdin.Scan()
+		showSecrets := strings.HasPrefix(strings.ToLower(stdin.Text()), "y")
+
+		policy, err := output.ParseMasking(masking)
+		if err != nil {
+			return fmt.Errorf("%w: %w", errUsage, err)
+		}
+		if showSecrets {
+			policy = output.MaskNone
+		}
+
+		output.PrettyPrintAccounts(accounts, pretty, policy)
+		if showQR {
+			err = output.DisplayQRCodesInTerminal(accounts, terminalQR)
+			if err != nil {
+				return fmt.Errorf("failed to display QR codes in terminal: %w", err)
+			}
+		}
+	default:
+		return fmt.Errorf("%w: invalid choice", errUsage)
+	}
+
+	return nil
+}
+
+func promptURI() string {
+	logging.Warnf("By using online QR decoders or untrusted ways of transferring the URI text,")
+	logging.Warnf("you risk someone storing the QR code or URI text and stealing your 2FA codes!")
+	logging.Warnf("Remember that the data contains the website, your email and the 2FA code!")
+	fmt.Fprintln(os.Stderr)
+
+	fmt.Fprintln(os.Stderr, "Enter the URI from Google Authenticator QR code.")
+	fmt.Fprintln(os.Stderr, "The URI looks like otpauth-migration://offline?data=...")
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "You can get it by exporting from Google Authenticator app, then scanning the QR with")
+	fmt.Fprintln(os.Stderr, "a QR code scanner app, and copying the text to your computer.")
+	fmt.Fprintln(os.Stderr)
+
+	fmt.Fprint(os.Stderr, "Enter URI: ")
+	stdin.Scan()
+	return stdin.Text()
+}
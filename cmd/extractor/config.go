@@ -0,0 +1,248 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/Zaphkiel-Ivanovna/GoogleAuthExtractor/internal/config"
+	"github.com/Zaphkiel-Ivanovna/GoogleAuthExtractor/internal/logging"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	configPath      string
+	configFile      *config.File
+	defaultExporter string
+
+	// settingSources records where the effective value of each setting came
+	// from, for "config show".
+	settingSources = make(map[string]string)
+)
+
+// Settings that exist only in the configuration file and the environment.
+const keyDefaultExporter = "default-exporter"
+
+// settingFlag is a flag that can be set from the configuration, under key.
+type settingFlag struct {
+	key  string
+	flag *pflag.Flag
+}
+
+// settingFlags lists the persistent flags of the root command under their
+// own name, and the flags of each subcommand as "<command>.<flag>".
+func settingFlags(root *cobra.Command) []settingFlag {
+	var list []settingFlag
+
+	root.PersistentFlags().VisitAll(func(f *pflag.Flag) {
+		if f.Name != "config" && f.Name != "help" {
+			list = append(list, settingFlag{f.Name, f})
+		}
+	})
+
+	for _, sub := range root.Commands() {
+		sub.Flags().VisitAll(func(f *pflag.Flag) {
+			if f.Name == "help" || root.PersistentFlags().Lookup(f.Name) == f {
+				return
+			}
+			list = append(list, settingFlag{sub.Name() + "." + f.Name, f})
+		})
+	}
+
+	return list
+}
+
+// loadConfig applies the configuration file and GAUTH_* environment
+// variables to every flag that was not given on the command line. The
+// precedence is: command line, environment, configuration file, default.
+func loadConfig(root *cobra.Command) error {
+	path := configPath
+	explicit := root.PersistentFlags().Changed("config")
+	if path == "" {
+		var err error
+		if path, err = config.DefaultPath(); err != nil {
+			return fmt.Errorf("failed to locate config file: %w", err)
+		}
+		explicit = os.Getenv(config.EnvConfig) != ""
+	}
+
+	file, err := config.Load(expandHome(path), explicit)
+	if err != nil {
+		return err
+	}
+	configFile = file
+
+	known := map[string]bool{keyDefaultExporter: true}
+	for _, setting := range settingFlags(root) {
+		known[setting.key] = true
+
+		if setting.flag.Changed {
+			settingSources[setting.key] = "flag"
+			continue
+		}
+
+		values, source, ok := file.Lookup(setting.key)
+		if !ok {
+			settingSources[setting.key] = "default"
+			continue
+		}
+
+		if _, isSlice := setting.flag.Value.(pflag.SliceValue); isSlice && strings.HasPrefix(source, "env ") {
+			values = strings.Split(values[0], ",")
+		}
+		for _, value := range values {
+			if err := setting.flag.Value.Set(value); err != nil {
+				return fmt.Errorf("%w: %s from %s: %w", config.ErrInvalidConfig, setting.key, source, err)
+			}
+		}
+		settingSources[setting.key] = source
+	}
+
+	if values, source, ok := file.Lookup(keyDefaultExporter); ok {
+		defaultExporter = values[0]
+		settingSources[keyDefaultExporter] = source
+	} else {
+		settingSources[keyDefaultExporter] = "default"
+	}
+
+	for _, key := range file.Keys() {
+		if !known[key] {
+			logging.Warnf("Unknown setting '%s' in config file %s", key, file.Path)
+		}
+	}
+
+	logging.Debugf("Using config file %s", file.Path)
+	return nil
+}
+
+// settingIsSet reports whether a setting was given on the command line, in
+// the configuration file or in the environment, rather than left at its
+// default.
+func settingIsSet(key string) bool {
+	source, ok := settingSources[key]
+	return ok && source != "default"
+}
+
+func expandHome(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, strings.TrimPrefix(path, "~"))
+}
+
+// resolveOutputPath places relative output paths under --output-dir.
+func resolveOutputPath(path string) string {
+	path = expandHome(path)
+	if outputDir == "" || filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(expandHome(outputDir), path)
+}
+
+// Settings whose values must never be printed.
+var secretSettings = map[string]bool{"uri": true, "fingerprint-key": true, "serve.token": true}
+
+func newConfigCommand(root *cobra.Command) *cobra.Command {
+	configCmd := &cobra.Command{
+		Use:   "config",
+		Short: "Inspect the configuration",
+		Long: `Inspect the configuration
+
+Every flag can also be set in a YAML configuration file, by default
+$XDG_CONFIG_HOME/gauth-extractor/config.yaml (override with --config or
+GAUTH_CONFIG), and through GAUTH_* environment variables. Global flags use
+their own name (masking: full, GAUTH_MASKING=full); subcommand flags live in
+a section named after the command (json: {file: x.json}, GAUTH_JSON_FILE).`,
+	}
+
+	showCmd := &cobra.Command{
+		Use:   "show",
+		Short: "Print the effective configuration",
+		Long: `Print the effective configuration
+
+The output merges defaults, the configuration file, GAUTH_* environment
+variables and command line flags, and notes where each value came from.
+It is valid YAML and can be used as a starting configuration file.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return printConfig(root)
+		},
+	}
+
+	configCmd.AddCommand(showCmd)
+	return configCmd
+}
+
+func printConfig(root *cobra.Command) error {
+	doc := &yaml.Node{Kind: yaml.MappingNode}
+	sections := make(map[string]*yaml.Node)
+	var sectionNames []string
+
+	add := func(parent *yaml.Node, name string, value *yaml.Node, key string) {
+		value.LineComment = settingSources[key]
+		parent.Content = append(parent.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: name}, value)
+	}
+
+	add(doc, keyDefaultExporter, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: defaultExporter}, keyDefaultExporter)
+
+	for _, setting := range settingFlags(root) {
+		value := flagNode(setting.flag)
+		if secretSettings[setting.key] && setting.flag.Value.String() != setting.flag.DefValue {
+			value = &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: "<redacted>"}
+		}
+
+		command, name, nested := strings.Cut(setting.key, ".")
+		if !nested {
+			add(doc, setting.key, value, setting.key)
+			continue
+		}
+
+		section, ok := sections[command]
+		if !ok {
+			section = &yaml.Node{Kind: yaml.MappingNode}
+			sections[command] = section
+			sectionNames = append(sectionNames, command)
+		}
+		add(section, name, value, setting.key)
+	}
+
+	sort.Strings(sectionNames)
+	for _, command := range sectionNames {
+		doc.Content = append(doc.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: command}, sections[command])
+	}
+
+	fmt.Printf("# Config file: %s\n", configFile.Path)
+	encoder := yaml.NewEncoder(os.Stdout)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(doc); err != nil {
+		return fmt.Errorf("failed to print configuration: %w", err)
+	}
+	return encoder.Close()
+}
+
+func flagNode(f *pflag.Flag) *yaml.Node {
+	if slice, ok := f.Value.(pflag.SliceValue); ok {
+		node := &yaml.Node{Kind: yaml.SequenceNode, Style: yaml.FlowStyle}
+		for _, item := range slice.GetSlice() {
+			node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: item})
+		}
+		return node
+	}
+
+	tag := "!!str"
+	switch f.Value.Type() {
+	case "bool":
+		tag = "!!bool"
+	case "int", "int64", "uint", "uint64":
+		tag = "!!int"
+	}
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: tag, Value: f.Value.String()}
+}
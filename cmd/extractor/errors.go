@@ -0,0 +1,112 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/Zaphkiel-Ivanovna/GoogleAuthExtractor/internal/config"
+	"github.com/Zaphkiel-Ivanovna/GoogleAuthExtractor/internal/decoder"
+	"github.com/Zaphkiel-Ivanovna/GoogleAuthExtractor/internal/edit"
+	"github.com/Zaphkiel-Ivanovna/GoogleAuthExtractor/internal/input"
+	"github.com/Zaphkiel-Ivanovna/GoogleAuthExtractor/internal/issuers"
+	"github.com/Zaphkiel-Ivanovna/GoogleAuthExtractor/internal/logging"
+	"github.com/Zaphkiel-Ivanovna/GoogleAuthExtractor/internal/output"
+	"github.com/Zaphkiel-Ivanovna/GoogleAuthExtractor/internal/selection"
+)
+
+var errUsage = errors.New("invalid usage")
+
+// Process exit statuses. Each error class gets its own status so scripts can
+// react without parsing messages; they are part of the CLI's interface and
+// must not be renumbered.
+const (
+	exitError              = 1
+	exitUsage              = 2
+	exitInvalidURI         = 3
+	exitMissingData        = 4
+	exitInvalidBase64      = 5
+	exitInvalidProtobuf    = 6
+	exitUnsupportedVersion = 7
+	exitNoQRCode           = 8
+	exitUnsupportedFormat  = 9
+	exitFileExists         = 10
+	exitInvalidConfig      = 11
+	exitNoMatch            = 12
+	exitAborted            = 13
+	exitInvalidRules       = 14
+	exitCodeMismatch       = 15
+	exitUnsafeDirectory    = 16
+	exitNoRecipients       = 17
+	exitInternal           = 18
+	exitImageTooLarge      = 19
+)
+
+type errorClass struct {
+	err  error
+	code string
+	exit int
+}
+
+var errorClasses = []errorClass{
+	{errUsage, "usage", exitUsage},
+	{decoder.ErrInvalidScheme, "invalid_scheme", exitInvalidURI},
+	{decoder.ErrInvalidURI, "invalid_uri", exitInvalidURI},
+	{decoder.ErrMissingData, "missing_data", exitMissingData},
+	{decoder.ErrInvalidBase64, "invalid_base64", exitInvalidBase64},
+	{decoder.ErrInvalidProtobuf, "invalid_protobuf", exitInvalidProtobuf},
+	{decoder.ErrUnsupportedVersion, "unsupported_version", exitUnsupportedVersion},
+	{input.ErrNoQRCode, "no_qr_code", exitNoQRCode},
+	{input.ErrUnsupportedFormat, "unsupported_format", exitUnsupportedFormat},
+	{input.ErrImageTooLarge, "image_too_large", exitImageTooLarge},
+	{output.ErrFileExists, "file_exists", exitFileExists},
+	{output.ErrNotTerminal, "usage", exitUsage},
+	{output.ErrUnsafeDirectory, "unsafe_directory", exitUnsafeDirectory},
+	{output.ErrNoRecipients, "no_recipients", exitNoRecipients},
+	{config.ErrInvalidConfig, "invalid_config", exitInvalidConfig},
+	{selection.ErrNoMatch, "no_match", exitNoMatch},
+	{edit.ErrNothingLeft, "no_match", exitNoMatch},
+	{selection.ErrPickerAborted, "aborted", exitAborted},
+	{edit.ErrEditAborted, "aborted", exitAborted},
+	{edit.ErrInvalidRules, "invalid_rules", exitInvalidRules},
+	{issuers.ErrInvalidCatalogue, "invalid_rules", exitInvalidRules},
+	{errCodeMismatch, "code_mismatch", exitCodeMismatch},
+}
+
+func classifyError(err error) (string, int) {
+	for _, class := range errorClasses {
+		if errors.Is(err, class.err) {
+			return class.code, class.exit
+		}
+	}
+	return "error", exitError
+}
+
+type jsonError struct {
+	Error struct {
+		Code     string `json:"code"`
+		Message  string `json:"message"`
+		ExitCode int    `json:"exitCode"`
+	} `json:"error"`
+}
+
+// reportError prints err on stderr in the requested format and returns the
+// process exit status for it.
+func reportError(err error, format string) int {
+	code, exit := classifyError(err)
+
+	if format == "json" {
+		var out jsonError
+		out.Error.Code = code
+		out.Error.Message = logging.Redact(err.Error())
+		out.Error.ExitCode = exit
+
+		data, _ := json.Marshal(out)
+		fmt.Fprintln(os.Stderr, string(data))
+		return exit
+	}
+
+	logging.Errorf("%v", err)
+	return exit
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+
+	"github.com/Zaphkiel-Ivanovna/GoogleAuthExtractor/internal/output"
+	"github.com/Zaphkiel-Ivanovna/GoogleAuthExtractor/internal/secmem"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+var (
+	exportFormat       string
+	exportFile         string
+	exportEncrypt      bool
+	exportPasswordFile string
+)
+
+func newExportCommand() *cobra.Command {
+	exportCmd := &cobra.Command{
+		Use:   "export",
+		Short: "Export accounts to the backup format of another authenticator app",
+		Long: `Export accounts to the backup format of another authenticator app
+
+Supported formats:
+  andotp  andOTP JSON backup (.json), or encrypted backup (.json.aes)
+  2fas    2FAS backup (.2fas), plain or password-protected
+  freeotp FreeOTP+ JSON backup (.json)
+  ente    Ente Auth plain text import, one otpauth URI per line (.txt)
+
+With --encrypt, the password is prompted for on the terminal, or read from
+the first line of --password-file. Import the file with the same password in
+the app.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			format, err := output.ParseBackupFormat(exportFormat)
+			if err != nil {
+				return fmt.Errorf("%w: %w", errUsage, err)
+			}
+
+			var password []byte
+			if exportEncrypt || exportPasswordFile != "" {
+				if !format.Encrypts() {
+					return fmt.Errorf("%w: %s backups cannot be encrypted", errUsage, format)
+				}
+				if password, err = readExportPassword(); err != nil {
+					return err
+				}
+				defer secmem.Wipe(password)
+			}
+
+			accounts, err := loadAccounts(args)
+			if err != nil {
+				return err
+			}
+
+			filename := exportFile
+			if filename == "" {
+				filename = "accounts" + format.Extension(len(password) > 0)
+			}
+
+			err = output.SaveBackup(accounts, resolveOutputPath(filename), format, password)
+			if err != nil {
+				return fmt.Errorf("failed to save %s backup: %w", format, err)
+			}
+			return nil
+		},
+	}
+
+	exportCmd.Flags().StringVar(&exportFormat, "format", string(output.BackupAndOTP), "Backup format (andotp, 2fas, freeotp, ente)")
+	exportCmd.Flags().StringVarP(&exportFile, "file", "f", "", "Output file path (default: accounts with the format's extension)")
+	exportCmd.Flags().BoolVarP(&exportEncrypt, "encrypt", "e", false, "Encrypt the backup with a password prompted for on the terminal")
+	exportCmd.Flags().StringVar(&exportPasswordFile, "password-file", "", "Encrypt the backup with the password on the first line of this file")
+
+	return exportCmd
+}
+
+// readExportPassword reads the backup password from --password-file, or
+// prompts for it twice on the terminal.
+func readExportPassword() ([]byte, error) {
+	if exportPasswordFile != "" {
+		data, err := os.ReadFile(expandHome(exportPasswordFile))
+		if err != nil {
+			return nil, fmt.Errorf("%w: failed to read password file: %w", errUsage, err)
+		}
+		password, _, _ := bytes.Cut(data, []byte("\n"))
+		password = bytes.TrimSuffix(password, []byte("\r"))
+		if len(password) == 0 {
+			return nil, fmt.Errorf("%w: password file '%s' is empty", errUsage, exportPasswordFile)
+		}
+		return password, nil
+	}
+
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return nil, fmt.Errorf("%w: --encrypt prompts for a password on a terminal; use --password-file instead", errUsage)
+	}
+
+	fmt.Fprint(os.Stderr, "Backup password: ")
+	password, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read password: %w", err)
+	}
+	if len(password) == 0 {
+		return nil, fmt.Errorf("%w: the password must not be empty", errUsage)
+	}
+
+	fmt.Fprint(os.Stderr, "Repeat password: ")
+	repeated, err := term.ReadPassword(fd)
+	defer secmem.Wipe(repeated)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read password: %w", err)
+	}
+	if !bytes.Equal(password, repeated) {
+		return nil, fmt.Errorf("%w: the passwords do not match", errUsage)
+	}
+	return password, nil
+}
@@ -1,32 +1,55 @@
 package main
 
 import (
-	"bufio"
 	"fmt"
+	"log/slog"
 	"os"
-	"strings"
 
-	"github.com/Zaphkiel-Ivanovna/GoogleAuthExtractor/internal/decoder"
-	"github.com/Zaphkiel-Ivanovna/GoogleAuthExtractor/internal/input"
+	"github.com/Zaphkiel-Ivanovna/GoogleAuthExtractor/internal/logging"
 	"github.com/Zaphkiel-Ivanovna/GoogleAuthExtractor/internal/output"
-	"github.com/fatih/color"
+	"github.com/Zaphkiel-Ivanovna/GoogleAuthExtractor/internal/secmem"
 	"github.com/spf13/cobra"
 )
 
 var (
-	qrImagePath      string
-	uri              string
+	qrImagePaths     []string
+	uriFlags         []string
 	interactiveInput bool
+	strictVersion    bool
+	errorFormat      string
+	quiet            bool
+	verbose          bool
+	outputDir        string
+	fileMode         string
+	overwrite        bool
+	noClobber        bool
+	suffixNames      bool
+	nameTemplate     string
+	masking          string
+	hardenMemory     bool
+	terminalQRMode   string
+	terminalQR       output.TerminalOptions
 
 	jsonFile       string
 	qrCodesDir     string
 	displayPretty  bool
 	displayQR      bool
-	saveToFiles    bool
+	saveJSON       bool
+	saveQR         bool
 	showFullSecret bool
+
+	qrImage        = output.DefaultQRImageOptions()
+	qrFormat       string
+	qrLevel        string
+	qrForeground   string
+	qrBackground   string
+	contactSheet   string
+	contactColumns int
 )
 
 func main() {
+	defer handlePanic()
+
 	rootCmd := &cobra.Command{
 		Use:   "gauth-extractor",
 		Short: "Extract TOTP/HOTP secrets from Google Authenticator export",
@@ -54,15 +77,23 @@ To use:
 This command displays the accounts in the terminal without saving them to files.
 You can customize the display using the --pretty and --qr flags.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			accounts, err := getAccounts(args)
+			accounts, err := loadAccounts(args)
 			if err != nil {
 				return err
 			}
 
-			output.PrettyPrintAccounts(accounts, displayPretty, showFullSecret)
+			policy, err := output.ParseMasking(masking)
+			if err != nil {
+				return fmt.Errorf("%w: %w", errUsage, err)
+			}
+			if showFullSecret {
+				policy = output.MaskNone
+			}
+
+			output.PrettyPrintAccounts(accounts, displayPretty, policy)
 
 			if displayQR {
-				err = output.DisplayQRCodesInTerminal(accounts)
+				err = output.DisplayQRCodesInTerminal(accounts, terminalQR)
 				if err != nil {
 					return fmt.Errorf("failed to display QR codes in terminal: %w", err)
 				}
@@ -80,21 +111,20 @@ You can customize the display using the --pretty and --qr flags.`,
 This command exports the extracted accounts to a JSON file
 which can be used for backup or for importing into other applications.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			accounts, err := getAccounts(args)
+			accounts, err := loadAccounts(args)
 			if err != nil {
 				return err
 			}
 
-			if saveToFiles {
-				err = output.SaveToJSON(accounts, jsonFile)
+			if saveJSON {
+				err = output.SaveToJSON(accounts, resolveOutputPath(jsonFile))
 				if err != nil {
 					return fmt.Errorf("failed to save JSON: %w", err)
 				}
 				return nil
 			}
 
-			output.PrintJSON(accounts)
-			return nil
+			return output.PrintJSON(accounts)
 		},
 	}
 
@@ -106,20 +136,28 @@ which can be used for backup or for importing into other applications.`,
 This command generates individual QR code images for each extracted account.
 These QR codes can be scanned by other authenticator apps.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			accounts, err := getAccounts(args)
+			accounts, err := loadAccounts(args)
 			if err != nil {
 				return err
 			}
 
-			if saveToFiles {
-				err = output.SaveToQRCodes(accounts, qrCodesDir)
+			if contactSheet != "" {
+				err = output.SaveContactSheet(accounts, resolveOutputPath(contactSheet), qrImage, contactColumns)
+				if err != nil {
+					return fmt.Errorf("failed to generate contact sheet: %w", err)
+				}
+				return nil
+			}
+
+			if saveQR {
+				err = output.SaveToQRCodes(accounts, resolveOutputPath(qrCodesDir), qrImage)
 				if err != nil {
 					return fmt.Errorf("failed to generate QR codes: %w", err)
 				}
 				return nil
 			}
 
-			err = output.DisplayQRCodesInTerminal(accounts)
+			err = output.DisplayQRCodesInTerminal(accounts, terminalQR)
 			if err != nil {
 				return fmt.Errorf("failed to display QR codes in terminal: %w", err)
 			}
@@ -127,155 +165,180 @@ These QR codes can be scanned by other authenticator apps.`,
 		},
 	}
 
-	rootCmd.PersistentFlags().StringVarP(&uri, "uri", "u", "", "Google Authenticator export URI (otpauth-migration://...)")
-	rootCmd.PersistentFlags().StringVarP(&qrImagePath, "qrimage", "q", "", "Path to image containing Google Authenticator QR code")
+	rootCmd.PersistentFlags().StringArrayVarP(&uriFlags, "uri", "u", nil, "Google Authenticator export URI (otpauth-migration://...) (repeatable)")
+	rootCmd.PersistentFlags().StringArrayVarP(&qrImagePaths, "qrimage", "q", nil, "Path to image or PDF containing Google Authenticator QR code(s) (animated GIF/APNG: every frame is scanned) (repeatable)")
 	rootCmd.PersistentFlags().BoolVarP(&interactiveInput, "interactive", "i", false, "Interactive mode (prompt for input)")
+	rootCmd.PersistentFlags().BoolVar(&strictVersion, "strict", false, "Fail instead of warning when an export has an unexpected payload version")
+	rootCmd.PersistentFlags().StringVar(&errorFormat, "error-format", "text", "Format of error messages on stderr (text, json)")
+	rootCmd.PersistentFlags().BoolVar(&quiet, "quiet", false, "Only print warnings and errors on stderr")
+	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "Print debug messages on stderr")
+	rootCmd.MarkFlagsMutuallyExclusive("quiet", "verbose")
+	rootCmd.PersistentFlags().StringVar(&configPath, "config", "", "Path to the YAML config file (default: $XDG_CONFIG_HOME/gauth-extractor/config.yaml)")
+	rootCmd.PersistentFlags().StringVar(&outputDir, "output-dir", "", "Base directory for relative output paths")
+	rootCmd.PersistentFlags().StringVar(&fileMode, "file-mode", "0600", "Permission mode of written files (octal)")
+	rootCmd.PersistentFlags().BoolVar(&overwrite, "overwrite", false, "Replace output files that already exist")
+	rootCmd.PersistentFlags().BoolVar(&noClobber, "no-clobber", false, "Keep output files that already exist and skip writing them")
+	rootCmd.PersistentFlags().BoolVar(&suffixNames, "suffix", false, "Write to \"name (2).ext\" when an output file already exists")
+	rootCmd.MarkFlagsMutuallyExclusive("overwrite", "no-clobber", "suffix")
+	rootCmd.PersistentFlags().StringVar(&nameTemplate, "name-template", output.DefaultNameTemplate, "Go template naming per-account output files, from .Issuer, .Name, .Index, .Type and .Hash")
+	rootCmd.PersistentFlags().BoolVar(&output.AllowUnsafeDir, "allow-unsafe-dir", false, "Write secrets to directories other users can read or that are synchronised to the cloud")
+	rootCmd.PersistentFlags().BoolVar(&dedupe, "dedupe", false, "Remove duplicate accounts, e.g. when merging exports from several phones")
+	rootCmd.PersistentFlags().StringSliceVar(&dedupeKeys, "dedupe-by", []string{"secret"}, "What makes accounts duplicates: secret, fingerprint, unique-id, label (comma-separated)")
+	rootCmd.PersistentFlags().StringVar(&fingerprintKey, "fingerprint-key", "", "Key for the secret fingerprints shown by view and written to exports")
+	rootCmd.PersistentFlags().StringVar(&fingerprintKeyFile, "fingerprint-key-file", "", "Read the fingerprint key from this file")
+	rootCmd.MarkFlagsMutuallyExclusive("fingerprint-key", "fingerprint-key-file")
+	rootCmd.PersistentFlags().StringVar(&mergePolicy, "merge-policy", "newest", "Which duplicate to keep: newest (last input), counter (highest HOTP counter), complete (most metadata)")
+	rootCmd.PersistentFlags().BoolVar(&normalizeIssuers, "normalize-issuers", false, "Replace issuers of well-known services by their canonical name (\"github.com\" becomes \"GitHub\")")
+	rootCmd.PersistentFlags().BoolVar(&inferIssuers, "infer-issuers", false, "Normalise issuers and fill in missing ones from \"Issuer:Name\" labels and email domains")
+	rootCmd.PersistentFlags().StringVar(&issuerRulesPath, "issuer-rules", "", "YAML file adding services to the issuer catalogue (implies --normalize-issuers)")
+	rootCmd.PersistentFlags().StringArrayVar(&selectCriteria.Issuers, "issuer", nil, "Only keep accounts whose issuer matches a glob or /regex/ (repeatable)")
+	rootCmd.PersistentFlags().StringArrayVar(&selectCriteria.Names, "name", nil, "Only keep accounts whose name matches a glob or /regex/ (repeatable)")
+	rootCmd.PersistentFlags().StringVar(&selectCriteria.Type, "type", "", "Only keep accounts of this type (totp, hotp)")
+	rootCmd.PersistentFlags().StringVar(&selectCriteria.Algorithm, "algorithm", "", "Only keep accounts using this algorithm (sha1, sha256, sha512, md5)")
+	rootCmd.PersistentFlags().StringVar(&selectCriteria.Indexes, "index", "", "Only keep accounts at these 1-based positions, e.g. 1-3,5,8-")
+	rootCmd.PersistentFlags().BoolVar(&pickAccounts, "pick", false, "Choose the accounts to keep from an interactive checklist")
+	rootCmd.PersistentFlags().StringVar(&editRulesPath, "edit-rules", "", "Rename, fix or drop accounts with the rules in this YAML file")
+	rootCmd.PersistentFlags().BoolVar(&editAccounts, "edit", false, "Edit names, issuers, digits and algorithms interactively before output")
+	rootCmd.PersistentFlags().StringVar(&terminalQRMode, "terminal-qr", string(output.TerminalAuto), "How QR codes are drawn in the terminal (auto, halfblock, sixel, kitty)")
+	rootCmd.PersistentFlags().BoolVar(&terminalQR.Invert, "invert-qr", false, "Draw terminal QR codes for a light background")
+	rootCmd.PersistentFlags().BoolVar(&terminalQR.Paged, "paged-qr", false, "Show terminal QR codes one at a time, wiping the screen on exit")
+	rootCmd.PersistentFlags().BoolVar(&hardenMemory, "harden", false, "Keep secrets in locked memory, wipe them on exit and disable core dumps")
+	rootCmd.PersistentFlags().StringVar(&masking, "masking", string(output.MaskPartial), "How secrets are masked in the terminal (partial, full, none)")
 
 	viewCmd.Flags().BoolVarP(&displayPretty, "pretty", "p", true, "Enable pretty formatted output (colorful and detailed)")
 	viewCmd.Flags().BoolVarP(&displayQR, "show-qr", "r", false, "Display QR codes in the terminal")
 	viewCmd.Flags().BoolVarP(&showFullSecret, "show-secrets", "s", false, "Show full secrets (USE WITH CAUTION)")
 
 	jsonCmd.Flags().StringVarP(&jsonFile, "file", "f", "accounts.json", "Output file path for JSON")
-	jsonCmd.Flags().BoolVarP(&saveToFiles, "save", "s", true, "Save to file (if false, prints to terminal)")
+	jsonCmd.Flags().BoolVarP(&saveJSON, "save", "s", true, "Save to file (if false, prints to terminal)")
 
 	qrCmd.Flags().StringVarP(&qrCodesDir, "dir", "d", "qrcodes", "Directory for saving QR code images")
-	qrCmd.Flags().BoolVarP(&saveToFiles, "save", "s", true, "Save to files (if false, displays in terminal)")
+	qrCmd.Flags().BoolVarP(&saveQR, "save", "s", true, "Save to files (if false, displays in terminal)")
+	qrCmd.Flags().StringVar(&qrFormat, "format", string(qrImage.Format), "Image format (png, svg)")
+	qrCmd.Flags().IntVar(&qrImage.Size, "size", qrImage.Size, "Width of each code in pixels, quiet zone included")
+	qrCmd.Flags().IntVar(&qrImage.QuietZone, "quiet-zone", qrImage.QuietZone, "Light border around each code, in modules")
+	qrCmd.Flags().StringVar(&qrLevel, "ec-level", "M", "Error correction level (L, M, Q, H)")
+	qrCmd.Flags().StringVar(&qrForeground, "fg", "#000000", "Colour of the dark modules and caption (hex, e.g. #000, #000000 or #00000080)")
+	qrCmd.Flags().StringVar(&qrBackground, "bg", "#ffffff", "Background colour (hex, e.g. #000, #000000 or #00000080)")
+	qrCmd.Flags().BoolVar(&qrImage.Caption, "caption", false, "Write the issuer and name under each code")
+	qrCmd.Flags().StringVar(&contactSheet, "contact-sheet", "", "Write all codes in a grid to this single image instead of one file per account")
+	qrCmd.Flags().IntVar(&contactColumns, "columns", 4, "Number of codes per row of the contact sheet")
 
 	rootCmd.AddCommand(viewCmd, jsonCmd, qrCmd)
+	rootCmd.AddCommand(newVerifyCommand())
+	rootCmd.AddCommand(newExportCommand())
+	rootCmd.AddCommand(newPassCommand())
+	rootCmd.AddCommand(newCSVCommand())
+	rootCmd.AddCommand(newServeCommand())
+	rootCmd.AddCommand(newConfigCommand(rootCmd))
+
+	exporters := map[string]*cobra.Command{"view": viewCmd, "json": jsonCmd, "qr": qrCmd}
 
 	rootCmd.RunE = func(cmd *cobra.Command, args []string) error {
 		if cmd.CalledAs() == "gauth-extractor" {
-			fmt.Println("Running in legacy mode...")
+			if defaultExporter != "" {
+				exporter, ok := exporters[defaultExporter]
+				if !ok {
+					return fmt.Errorf("%w: unknown default exporter '%s' (expected view, json or qr)", errUsage, defaultExporter)
+				}
+				return exporter.RunE(exporter, args)
+			}
+
+			logging.Infof("Running in legacy mode...")
 			return handleLegacyCommand(args)
 		}
 		return cmd.Help()
 	}
 
-	if err := rootCmd.Execute(); err != nil {
-		fmt.Println(err)
-		os.Exit(1)
-	}
-}
+	rootCmd.SilenceErrors = true
+	rootCmd.SilenceUsage = true
+	rootCmd.SetFlagErrorFunc(func(cmd *cobra.Command, err error) error {
+		return fmt.Errorf("%w: %w", errUsage, err)
+	})
+	rootCmd.PersistentPreRunE = func(cmd *cobra.Command, args []string) error {
+		if err := loadConfig(rootCmd); err != nil {
+			return err
+		}
 
-func getAccounts(args []string) ([]decoder.Account, error) {
-	extractedURI := ""
+		if errorFormat != "text" && errorFormat != "json" {
+			return fmt.Errorf("%w: unknown error format '%s' (expected text or json)", errUsage, errorFormat)
+		}
+
+		switch {
+		case quiet:
+			logging.SetLevel(slog.LevelWarn)
+		case verbose:
+			logging.SetLevel(slog.LevelDebug)
+		}
+
+		if hardenMemory {
+			if err := secmem.DisableCoreDumps(); err != nil {
+				logging.Warnf("%v", err)
+			}
+		}
 
-	if qrImagePath != "" {
-		var err error
-		extractedURI, err = input.ExtractQRCodeFromImage(qrImagePath)
+		mode, err := output.ParseFileMode(fileMode)
 		if err != nil {
-			return nil, fmt.Errorf("failed to extract QR code from image: %w", err)
+			return fmt.Errorf("%w: %w", errUsage, err)
+		}
+		output.FileMode = mode
+
+		switch {
+		case overwrite:
+			output.Clobber = output.ClobberOverwrite
+		case noClobber:
+			output.Clobber = output.ClobberSkip
+		case suffixNames:
+			output.Clobber = output.ClobberSuffix
 		}
-		color.Green("Successfully extracted QR code from image")
-	} else if uri != "" {
 
-		extractedURI = uri
-	} else {
+		if output.FileNames, err = output.ParseNameTemplate(nameTemplate); err != nil {
+			return fmt.Errorf("%w: %w", errUsage, err)
+		}
 
-		if !interactiveInput && len(args) == 0 {
-			interactiveInput = true
-		} else if len(args) > 0 {
-			extractedURI = args[0]
+		if _, err := output.ParseMasking(masking); err != nil {
+			return fmt.Errorf("%w: %w", errUsage, err)
 		}
 
-		if interactiveInput {
-			extractedURI = promptURI()
+		if terminalQR.Mode, err = output.ParseTerminalMode(terminalQRMode); err != nil {
+			return fmt.Errorf("%w: %w", errUsage, err)
 		}
-	}
 
-	if extractedURI == "" {
-		return nil, fmt.Errorf("no URI provided. Use --uri, --qrimage, or --interactive flags")
+		if err := parseQRImageOptions(); err != nil {
+			return fmt.Errorf("%w: %w", errUsage, err)
+		}
+		return nil
 	}
 
-	accounts, err := decoder.DecodeExportURI(extractedURI)
+	err := rootCmd.Execute()
+	secmem.WipeAll()
 	if err != nil {
-		return nil, fmt.Errorf("failed to decode URI: %w", err)
+		os.Exit(reportError(err, errorFormat))
 	}
-
-	color.Green("Successfully decoded %d accounts", len(accounts))
-	return accounts, nil
 }
 
-func handleLegacyCommand(args []string) error {
-	accounts, err := getAccounts(args)
-	if err != nil {
+func parseQRImageOptions() error {
+	var err error
+	if qrImage.Format, err = output.ParseImageFormat(qrFormat); err != nil {
 		return err
 	}
-
-	fmt.Println("\nHow would you like to output the accounts?")
-	fmt.Println("1. Save to JSON file")
-	fmt.Println("2. Print JSON to terminal")
-	fmt.Println("3. Generate QR code files")
-	fmt.Println("4. Display in terminal")
-	fmt.Print("\nEnter option (1-4): ")
-
-	scanner := bufio.NewScanner(os.Stdin)
-	scanner.Scan()
-	choice := strings.TrimSpace(scanner.Text())
-
-	switch choice {
-	case "1":
-		fmt.Printf("Save to JSON file '%s'? [y/N]: ", jsonFile)
-		scanner.Scan()
-		if strings.HasPrefix(strings.ToLower(scanner.Text()), "y") {
-			err = output.SaveToJSON(accounts, jsonFile)
-			if err != nil {
-				return fmt.Errorf("failed to save JSON: %w", err)
-			}
-		}
-	case "2":
-		output.PrintJSON(accounts)
-	case "3":
-		fmt.Printf("Save QR codes to directory '%s'? [y/N]: ", qrCodesDir)
-		scanner.Scan()
-		if strings.HasPrefix(strings.ToLower(scanner.Text()), "y") {
-			err = output.SaveToQRCodes(accounts, qrCodesDir)
-			if err != nil {
-				return fmt.Errorf("failed to generate QR codes: %w", err)
-			}
-		}
-	case "4":
-		fmt.Print("Use pretty formatting? [Y/n]: ")
-		scanner.Scan()
-		pretty := !strings.HasPrefix(strings.ToLower(scanner.Text()), "n")
-
-		fmt.Print("Show QR codes in terminal? [y/N]: ")
-		scanner.Scan()
-		showQR := strings.HasPrefix(strings.ToLower(scanner.Text()), "y")
-
-		fmt.Print("Show full secrets? (CAUTION: Secrets will be visible) [y/N]: ")
-		scanner.Scan()
-		showSecrets := strings.HasPrefix(strings.ToLower(scanner.Text()), "y")
-
-		output.PrettyPrintAccounts(accounts, pretty, showSecrets)
-		if showQR {
-			err = output.DisplayQRCodesInTerminal(accounts)
-			if err != nil {
-				return fmt.Errorf("failed to display QR codes in terminal: %w", err)
-			}
-		}
-	default:
-		return fmt.Errorf("invalid choice")
+	if qrImage.Level, err = output.ParseRecoveryLevel(qrLevel); err != nil {
+		return err
+	}
+	if qrImage.Foreground, err = output.ParseColor(qrForeground); err != nil {
+		return err
+	}
+	if qrImage.Background, err = output.ParseColor(qrBackground); err != nil {
+		return err
 	}
 
+	switch {
+	case qrImage.Size < 21 || qrImage.Size > output.MaxQRImageSize:
+		return fmt.Errorf("invalid QR code size %d: expected 21 to %d pixels", qrImage.Size, output.MaxQRImageSize)
+	case qrImage.QuietZone < 0:
+		return fmt.Errorf("invalid quiet zone %d: expected 0 or more modules", qrImage.QuietZone)
+	case contactColumns < 1:
+		return fmt.Errorf("invalid number of columns %d: expected at least 1", contactColumns)
+	}
 	return nil
 }
-
-func promptURI() string {
-	color.Red("WARNING: By using online QR decoders or untrusted ways of transferring the URI text,")
-	color.Red("you risk someone storing the QR code or URI text and stealing your 2FA codes!")
-	color.Red("Remember that the data contains the website, your email and the 2FA code!")
-	fmt.Println()
-
-	fmt.Println("Enter the URI from Google Authenticator QR code.")
-	fmt.Println("The URI looks like otpauth-migration://offline?data=...")
-	fmt.Println("")
-	fmt.Println("You can get it by exporting from Google Authenticator app, then scanning the QR with")
-	fmt.Println("a QR code scanner app, and copying the text to your computer.")
-	fmt.Println("")
-
-	fmt.Print("Enter URI: ")
-	scanner := bufio.NewScanner(os.Stdin)
-	scanner.Scan()
-	return scanner.Text()
-}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"runtime/debug"
+
+	"github.com/Zaphkiel-Ivanovna/GoogleAuthExtractor/internal/logging"
+	"github.com/Zaphkiel-Ivanovna/GoogleAuthExtractor/internal/secmem"
+)
+
+// handlePanic turns a panic into an error report with secrets scrubbed from
+// the panic value and stack trace, instead of the runtime's raw dump. It must
+// be deferred at the top of main.
+//
+// recover only sees panics of its own goroutine, so a panic in any other
+// goroutine still crashes with the raw dump. Goroutines started by the
+// extractor must not be able to panic, or must defer handlePanic themselves.
+// The HTTP server's handlers are an exception: net/http recovers their panics
+// and logs them through its ErrorLog, which goes through the redacting
+// logger.
+func handlePanic() {
+	if value := recover(); value != nil {
+		os.Exit(reportPanic(os.Stderr, value, debug.Stack(), errorFormat, verbose))
+	}
+}
+
+// reportPanic wipes secrets held in memory and prints the panic on w. The
+// stack trace is only printed with --verbose.
+func reportPanic(w io.Writer, value any, stack []byte, format string, withStack bool) int {
+	secmem.WipeAll()
+
+	message := "internal error: " + logging.Redact(fmt.Sprint(value))
+	if format == "json" {
+		var out jsonError
+		out.Error.Code = "internal_error"
+		out.Error.Message = message
+		out.Error.ExitCode = exitInternal
+
+		data, _ := json.Marshal(out)
+		fmt.Fprintln(w, string(data))
+		return exitInternal
+	}
+
+	fmt.Fprintf(w, "Error: %s\n", message)
+	if withStack {
+		fmt.Fprintln(w, logging.Redact(string(stack)))
+	} else {
+		fmt.Fprintln(w, "This is a bug; run again with --verbose for a stack trace (secrets are redacted) and report it.")
+	}
+	return exitInternal
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestReportPanic(t *testing.T) {
+	uri := "otpauth-migration://offline?data=CiIKCkhlbGwPId6tvugSDlRlc3QgYWNjb3VudCAxIAEoATACEAEYASAAKJ2G1g0%3D"
+	value := fmt.Errorf("unexpected account in %s with secret JBSWY3DPEHPK3PXP", uri)
+	stack := []byte("goroutine 1 [running]:\nmain.decode({0xc000012345, 0x40}, \"SGVsbG8h3q2+7w==\")\n")
+
+	for _, format := range []string{"text", "json"} {
+		t.Run(format, func(t *testing.T) {
+			var b bytes.Buffer
+			if exit := reportPanic(&b, value, stack, format, true); exit != exitInternal {
+				t.Errorf("Expected exit status %d, got %d", exitInternal, exit)
+			}
+
+			out := b.String()
+			for _, secret := range []string{"CiIKCkhlbGwPId6tvugSDlRlc3Qg", "JBSWY3DPEHPK3PXP", "SGVsbG8h3q2+7w=="} {
+				if strings.Contains(out, secret) {
+					t.Errorf("Expected %q to be redacted, got:\n%s", secret, out)
+				}
+			}
+			if !strings.Contains(out, "internal error: unexpected account") {
+				t.Errorf("Expected the panic message, got:\n%s", out)
+			}
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/Zaphkiel-Ivanovna/GoogleAuthExtractor/internal/output"
+	"github.com/spf13/cobra"
+)
+
+var (
+	passStore    string
+	passKeyrings []string
+)
+
+func newPassCommand() *cobra.Command {
+	passCmd := &cobra.Command{
+		Use:   "pass",
+		Short: "Export accounts to a pass password store as pass-otp entries",
+		Long: `Export accounts to a pass password store as pass-otp entries
+
+Each account is written to otp/<issuer>/<name>.gpg in the password store,
+holding the otpauth URI that 'pass otp' reads. Entries are encrypted to the
+recipients of the nearest .gpg-id file, like 'pass insert' does, without
+needing gpg.
+
+Recipients are looked up in --keyring files, which hold public keys exported
+with 'gpg --export'. The GnuPG keyring is used when it is in the legacy
+pubring.gpg format.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			accounts, err := loadAccounts(args)
+			if err != nil {
+				return err
+			}
+
+			keyrings := passKeyrings
+			if len(keyrings) == 0 {
+				keyrings = output.DefaultKeyrings()
+			}
+
+			err = output.SaveToPass(accounts, output.PassOptions{Store: resolveOutputPath(passStore), Keyrings: keyrings})
+			if err != nil {
+				return fmt.Errorf("failed to export to pass: %w", err)
+			}
+			return nil
+		},
+	}
+
+	passCmd.Flags().StringVar(&passStore, "store", output.PassStoreDir(), "Password store directory")
+	passCmd.Flags().StringArrayVar(&passKeyrings, "keyring", nil, "File of OpenPGP public keys for the .gpg-id recipients (repeatable)")
+
+	return passCmd
+}
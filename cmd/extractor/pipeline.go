@@ -0,0 +1,206 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/Zaphkiel-Ivanovna/GoogleAuthExtractor/internal/decoder"
+	"github.com/Zaphkiel-Ivanovna/GoogleAuthExtractor/internal/edit"
+	"github.com/Zaphkiel-Ivanovna/GoogleAuthExtractor/internal/issuers"
+	"github.com/Zaphkiel-Ivanovna/GoogleAuthExtractor/internal/logging"
+	"github.com/Zaphkiel-Ivanovna/GoogleAuthExtractor/internal/merge"
+	"github.com/Zaphkiel-Ivanovna/GoogleAuthExtractor/internal/selection"
+)
+
+var (
+	selectCriteria selection.Criteria
+	pickAccounts   bool
+	editRulesPath  string
+	editAccounts   bool
+
+	dedupe      bool
+	dedupeKeys  []string
+	mergePolicy string
+
+	fingerprintKey     string
+	fingerprintKeyFile string
+
+	normalizeIssuers bool
+	inferIssuers     bool
+	issuerRulesPath  string
+)
+
+// loadAccounts decodes the accounts from the input and runs them through
+// the processing steps shared by every output command.
+func loadAccounts(args []string) ([]decoder.Account, error) {
+	sources, err := getAccounts(args)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := setFingerprints(sources); err != nil {
+		return nil, err
+	}
+
+	accounts, err := mergeSources(sources)
+	if err != nil {
+		return nil, err
+	}
+
+	if accounts, err = normalizeAccounts(accounts); err != nil {
+		return nil, err
+	}
+
+	if accounts, err = selectAccounts(accounts); err != nil {
+		return nil, err
+	}
+
+	return applyEdits(accounts)
+}
+
+// setFingerprints fingerprints every account with the key from
+// --fingerprint-key or --fingerprint-key-file. Without a key, accounts are
+// left without fingerprints: an unkeyed fingerprint can be checked against a
+// guessed secret.
+func setFingerprints(sources []merge.Source) error {
+	key, err := readFingerprintKey()
+	if err != nil || len(key) == 0 {
+		return err
+	}
+
+	for _, source := range sources {
+		if err := decoder.SetFingerprints(source.Accounts, key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readFingerprintKey returns the key given with --fingerprint-key or
+// --fingerprint-key-file.
+func readFingerprintKey() ([]byte, error) {
+	if fingerprintKeyFile == "" {
+		return []byte(fingerprintKey), nil
+	}
+	data, err := os.ReadFile(expandHome(fingerprintKeyFile))
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to read fingerprint key: %w", errUsage, err)
+	}
+	key := bytes.TrimRight(data, "\r\n")
+	if len(key) == 0 {
+		return nil, fmt.Errorf("%w: fingerprint key file %s is empty", errUsage, fingerprintKeyFile)
+	}
+	return key, nil
+}
+
+func hasFingerprintKey() bool {
+	return fingerprintKey != "" || fingerprintKeyFile != ""
+}
+
+// mergeSources concatenates the accounts of all inputs, removing duplicates
+// when --dedupe is set.
+func mergeSources(sources []merge.Source) ([]decoder.Account, error) {
+	if !dedupe {
+		var accounts []decoder.Account
+		for _, source := range sources {
+			accounts = append(accounts, source.Accounts...)
+		}
+		return accounts, nil
+	}
+
+	keys, err := merge.ParseKeys(dedupeKeys)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", errUsage, err)
+	}
+	if slices.Contains(keys, merge.KeyFingerprint) && !hasFingerprintKey() {
+		return nil, fmt.Errorf("%w: --dedupe-by fingerprint needs --fingerprint-key or --fingerprint-key-file", errUsage)
+	}
+	policy, err := merge.ParsePolicy(mergePolicy)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", errUsage, err)
+	}
+
+	result := merge.Merge(sources, merge.Options{Keys: keys, Policy: policy})
+	kept := "all of them are kept"
+	if slices.Contains(keys, merge.KeyLabel) {
+		kept = fmt.Sprintf("only one is kept (--merge-policy=%s)", policy)
+	}
+	for _, conflict := range result.Conflicts {
+		logging.Warnf("'%s' has different secrets in %s: %s", conflict.Label, strings.Join(conflict.Sources, ", "), kept)
+	}
+	logging.Infof("Removed %d duplicate accounts, %d accounts left", result.Duplicates, len(result.Accounts))
+
+	return result.Accounts, nil
+}
+
+func normalizeAccounts(accounts []decoder.Account) ([]decoder.Account, error) {
+	if !normalizeIssuers && !inferIssuers && issuerRulesPath == "" {
+		return accounts, nil
+	}
+
+	catalogue := issuers.Builtin()
+	if issuerRulesPath != "" {
+		rules, err := issuers.LoadRules(expandHome(issuerRulesPath))
+		if err != nil {
+			return nil, err
+		}
+		catalogue.Extend(rules)
+	}
+
+	accounts, changed := catalogue.Normalize(accounts, inferIssuers)
+	logging.Infof("Normalised the issuer of %d accounts", changed)
+	return accounts, nil
+}
+
+func selectAccounts(accounts []decoder.Account) ([]decoder.Account, error) {
+	if selectCriteria.IsZero() && !pickAccounts {
+		return accounts, nil
+	}
+
+	selected, err := selection.Apply(accounts, selectCriteria)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", errUsage, err)
+	}
+
+	if pickAccounts && len(selected) > 0 {
+		selected, err = selection.Pick(selected, stdin, os.Stderr)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if len(selected) == 0 {
+		return nil, selection.ErrNoMatch
+	}
+
+	logging.Infof("Selected %d of %d accounts", len(selected), len(accounts))
+	return selected, nil
+}
+
+func applyEdits(accounts []decoder.Account) ([]decoder.Account, error) {
+	if editRulesPath != "" {
+		rules, err := edit.LoadRules(expandHome(editRulesPath))
+		if err != nil {
+			return nil, err
+		}
+
+		var changed, dropped int
+		accounts, changed, dropped = rules.Apply(accounts)
+		logging.Infof("Edit rules changed %d and dropped %d accounts", changed, dropped)
+	}
+
+	if editAccounts && len(accounts) > 0 {
+		var err error
+		if accounts, err = edit.Interactive(accounts, stdin, os.Stderr); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(accounts) == 0 {
+		return nil, edit.ErrNothingLeft
+	}
+	return accounts, nil
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/Zaphkiel-Ivanovna/GoogleAuthExtractor/internal/decoder"
+	"github.com/Zaphkiel-Ivanovna/GoogleAuthExtractor/internal/logging"
+	"github.com/Zaphkiel-Ivanovna/GoogleAuthExtractor/internal/server"
+	"github.com/spf13/cobra"
+)
+
+var (
+	serveListen    string
+	serveSocket    string
+	serveToken     string
+	serveTokenFile string
+	serveMaxBody   int64
+)
+
+// Tokens shorter than this are too easy to guess.
+const minTokenLength = 16
+
+func newServeCommand() *cobra.Command {
+	serveCmd := &cobra.Command{
+		Use:   "serve",
+		Short: "Serve a local HTTP JSON API for decoding exports and generating codes",
+		Long: `Serve a local HTTP JSON API for decoding exports and generating codes
+
+The API listens on a loopback address, or on a unix socket with --socket,
+and every request must carry the token as "Authorization: Bearer <token>".
+The token is read from --token-file or GAUTH_SERVE_TOKEN; without either, a
+random token is printed on stderr at startup.
+
+Endpoints:
+  POST   /v1/decode    Decode {"uris": [...], "store": true} or an image or
+                       PDF body (?store=true) and return the accounts
+  GET    /v1/accounts  List stored accounts, without their secrets
+  GET    /v1/codes     Current codes of stored accounts (?id= to filter)
+  DELETE /v1/accounts  Forget the stored accounts
+
+Accounts given with --uri, --qrimage or as arguments are stored at startup.
+Stored accounts are kept in memory only. Request bodies are never logged.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			token, err := readServeToken()
+			if err != nil {
+				return err
+			}
+
+			key, err := readFingerprintKey()
+			if err != nil {
+				return err
+			}
+			if len(key) == 0 {
+				// Account IDs made without a key would be plain hashes of
+				// the secrets.
+				if key, err = decoder.NewFingerprintKey(); err != nil {
+					return err
+				}
+			}
+
+			var accounts []decoder.Account
+			if len(uriFlags) > 0 || len(qrImagePaths) > 0 || len(args) > 0 {
+				if accounts, err = loadAccounts(args); err != nil {
+					return err
+				}
+				if err := decoder.SetFingerprints(accounts, key); err != nil {
+					return err
+				}
+			}
+
+			listener, err := listen()
+			if err != nil {
+				return err
+			}
+
+			srv := server.New(accounts, server.Options{
+				Token:          token,
+				MaxBodySize:    serveMaxBody,
+				LocalHostOnly:  serveSocket == "",
+				Decode:         decodeOptions(),
+				FingerprintKey: key,
+			})
+			defer srv.Close()
+
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+
+			logging.Infof("Serving %d stored accounts; press Ctrl+C to stop", len(accounts))
+			return srv.Serve(ctx, listener)
+		},
+	}
+
+	serveCmd.Flags().StringVar(&serveListen, "listen", "127.0.0.1:8765", "Loopback address and port to listen on")
+	serveCmd.Flags().StringVar(&serveSocket, "socket", "", "Listen on this unix socket instead of a TCP port")
+	serveCmd.Flags().StringVar(&serveToken, "token", "", "API token (prefer --token-file or GAUTH_SERVE_TOKEN, as arguments are visible to other users)")
+	serveCmd.Flags().StringVar(&serveTokenFile, "token-file", "", "Read the API token from this file")
+	serveCmd.Flags().Int64Var(&serveMaxBody, "max-body", server.DefaultMaxBodySize, "Largest accepted request body, in bytes")
+	serveCmd.MarkFlagsMutuallyExclusive("listen", "socket")
+	serveCmd.MarkFlagsMutuallyExclusive("token", "token-file")
+
+	return serveCmd
+}
+
+// readServeToken returns the configured token, or a new random one that is
+// printed for the user.
+func readServeToken() (string, error) {
+	token := serveToken
+	if serveTokenFile != "" {
+		data, err := os.ReadFile(expandHome(serveTokenFile))
+		if err != nil {
+			return "", fmt.Errorf("%w: failed to read token: %w", errUsage, err)
+		}
+		token = strings.TrimRight(string(data), "\r\n")
+	}
+
+	if token == "" {
+		random := make([]byte, 32)
+		if _, err := rand.Read(random); err != nil {
+			return "", fmt.Errorf("failed to generate token: %w", err)
+		}
+		token = hex.EncodeToString(random)
+		// Printed directly: log output is redacted.
+		fmt.Fprintf(os.Stderr, "API token: %s\n", token)
+	}
+
+	if len(token) < minTokenLength {
+		return "", fmt.Errorf("%w: token must be at least %d characters", errUsage, minTokenLength)
+	}
+	if serveMaxBody <= 0 {
+		return "", fmt.Errorf("%w: --max-body must be positive", errUsage)
+	}
+	return token, nil
+}
+
+// listen opens the unix socket, readable by the current user only, or the
+// TCP address, which must be a loopback address.
+func listen() (net.Listener, error) {
+	if serveSocket != "" {
+		path := expandHome(serveSocket)
+		if info, err := os.Lstat(path); err == nil {
+			if info.Mode()&os.ModeSocket == 0 {
+				return nil, fmt.Errorf("%w: %s exists and is not a socket", errUsage, path)
+			}
+			if conn, err := net.DialTimeout("unix", path, time.Second); err == nil {
+				conn.Close()
+				return nil, fmt.Errorf("%w: %s is in use by another server", errUsage, path)
+			}
+			// Left over by a server that did not shut down cleanly.
+			if err := os.Remove(path); err != nil {
+				return nil, fmt.Errorf("failed to remove stale socket: %w", err)
+			}
+		}
+
+		listener, err := listenUnix(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to listen: %w", err)
+		}
+		if err := os.Chmod(path, 0600); err != nil {
+			listener.Close()
+			return nil, fmt.Errorf("failed to restrict socket permissions: %w", err)
+		}
+		logging.Infof("Listening on unix socket %s", path)
+		return listener, nil
+	}
+
+	host, _, err := net.SplitHostPort(serveListen)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid --listen address: %w", errUsage, err)
+	}
+	if !server.IsLoopbackHost(host) {
+		return nil, fmt.Errorf("%w: refusing to listen on %s: only loopback addresses are allowed", errUsage, serveListen)
+	}
+
+	listener, err := net.Listen("tcp", serveListen)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen: %w", err)
+	}
+	logging.Infof("Listening on http://%s", listener.Addr())
+	return listener, nil
+}
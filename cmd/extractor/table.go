@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/Zaphkiel-Ivanovna/GoogleAuthExtractor/internal/output"
+	"github.com/spf13/cobra"
+)
+
+var (
+	tableFile    string
+	saveTable    bool
+	tableTSV     bool
+	tableColumns []string
+	tableSecrets string
+)
+
+func newCSVCommand() *cobra.Command {
+	var defaultColumns []string
+	for _, column := range output.DefaultColumns {
+		defaultColumns = append(defaultColumns, string(column))
+	}
+
+	csvCmd := &cobra.Command{
+		Use:   "csv",
+		Short: "Export accounts to a CSV or TSV table",
+		Long: `Export accounts to a CSV or TSV table
+
+This command writes one row per account, with the columns chosen with
+--columns: issuer, name, type, algorithm, digits, counter, secret (base32),
+uri (otpauth URI) and fingerprint (a keyed hash identifying the secret).
+
+The secret is left out by default so that the table can serve as an
+inventory. With --secrets=fingerprint the secret and uri columns hold the
+fingerprint instead, and with --secrets=omit they are dropped. Fingerprints
+need --fingerprint-key or --fingerprint-key-file; without a key, the
+fingerprint column is left out of the defaults.
+
+CSV follows RFC 4180. Values that a spreadsheet would run as a formula are
+prefixed with a quote.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			columns, err := output.ParseColumns(tableColumns)
+			if err != nil {
+				return fmt.Errorf("%w: %w", errUsage, err)
+			}
+			secrets, err := output.ParseSecretPolicy(tableSecrets)
+			if err != nil {
+				return fmt.Errorf("%w: %w", errUsage, err)
+			}
+			if !hasFingerprintKey() && !settingIsSet(cmd.Name()+".columns") {
+				columns = slices.DeleteFunc(columns, func(c output.Column) bool { return c == output.ColumnFingerprint })
+			}
+			opts := output.TableOptions{Columns: columns, Secrets: secrets, TSV: tableTSV}
+			if !hasFingerprintKey() && opts.NeedsFingerprints() {
+				return fmt.Errorf("%w: fingerprints need --fingerprint-key or --fingerprint-key-file", errUsage)
+			}
+
+			accounts, err := loadAccounts(args)
+			if err != nil {
+				return err
+			}
+
+			if !saveTable {
+				return output.PrintTable(accounts, opts)
+			}
+
+			filename := tableFile
+			if filename == "" {
+				filename = "accounts.csv"
+				if tableTSV {
+					filename = "accounts.tsv"
+				}
+			}
+			err = output.SaveToTable(accounts, resolveOutputPath(filename), opts)
+			if err != nil {
+				return fmt.Errorf("failed to save table: %w", err)
+			}
+			return nil
+		},
+	}
+
+	csvCmd.Flags().StringVarP(&tableFile, "file", "f", "", "Output file path (default: accounts.csv, or accounts.tsv with --tsv)")
+	csvCmd.Flags().BoolVarP(&saveTable, "save", "s", true, "Save to file (if false, prints to terminal)")
+	csvCmd.Flags().BoolVar(&tableTSV, "tsv", false, "Separate fields with tabs instead of commas")
+	csvCmd.Flags().StringSliceVar(&tableColumns, "columns", defaultColumns, "Columns to write: issuer, name, type, algorithm, digits, counter, secret, uri, fingerprint (comma-separated)")
+	csvCmd.Flags().StringVar(&tableSecrets, "secrets", string(output.SecretsPlain), "How the secret and uri columns are written (plain, fingerprint, omit)")
+
+	return csvCmd
+}
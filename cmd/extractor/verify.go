@@ -0,0 +1,131 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Zaphkiel-Ivanovna/GoogleAuthExtractor/internal/decoder"
+	"github.com/Zaphkiel-Ivanovna/GoogleAuthExtractor/internal/logging"
+	"github.com/Zaphkiel-Ivanovna/GoogleAuthExtractor/internal/otp"
+	"github.com/Zaphkiel-Ivanovna/GoogleAuthExtractor/internal/selection"
+	"github.com/spf13/cobra"
+)
+
+var errCodeMismatch = errors.New("code does not match the extracted secret")
+
+var (
+	verifyCode      string
+	verifyWindow    int
+	verifyLookAhead int
+)
+
+func newVerifyCommand() *cobra.Command {
+	verifyCmd := &cobra.Command{
+		Use:   "verify",
+		Short: "Check an extracted secret against the code shown on the phone",
+		Long: `Check an extracted secret against the code shown on the phone
+
+Before deleting accounts from Google Authenticator, make sure the extracted
+secret is right: enter the code the app currently shows for the account, and
+it is compared with the codes generated from the extracted secret.
+
+TOTP codes are accepted within --window time steps (30 seconds each) of this
+computer's clock, and the detected clock drift is reported. HOTP codes are
+accepted up to --look-ahead counters after the exported counter.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			accounts, err := loadAccounts(args)
+			if err != nil {
+				return err
+			}
+			return verifyAccount(accounts)
+		},
+	}
+
+	verifyCmd.Flags().StringVarP(&verifyCode, "code", "c", "", "Code shown by the phone (prompted when omitted)")
+	verifyCmd.Flags().IntVarP(&verifyWindow, "window", "w", 1, "TOTP time steps accepted before and after the current one")
+	verifyCmd.Flags().IntVar(&verifyLookAhead, "look-ahead", 10, "HOTP counters accepted after the exported one")
+
+	return verifyCmd
+}
+
+func verifyAccount(accounts []decoder.Account) error {
+	if verifyWindow < 0 || verifyLookAhead < 0 {
+		return fmt.Errorf("%w: --window and --look-ahead must not be negative", errUsage)
+	}
+
+	account := accounts[0]
+	if len(accounts) > 1 {
+		fmt.Fprintln(os.Stderr, "\nWhich account do you want to verify?")
+		for i, a := range accounts {
+			fmt.Fprintf(os.Stderr, "%2d. %s\n", i+1, selection.Label(a))
+		}
+		fmt.Fprintf(os.Stderr, "\nEnter account number (1-%d): ", len(accounts))
+
+		stdin.Scan()
+		index, err := strconv.Atoi(strings.TrimSpace(stdin.Text()))
+		if err != nil || index < 1 || index > len(accounts) {
+			return fmt.Errorf("%w: invalid account number", errUsage)
+		}
+		account = accounts[index-1]
+	}
+
+	secret, err := account.SecretBytes()
+	if err != nil {
+		return err
+	}
+
+	code := verifyCode
+	if code == "" {
+		fmt.Fprintf(os.Stderr, "Enter the code Google Authenticator shows for %s: ", selection.Label(account))
+		stdin.Scan()
+		code = stdin.Text()
+	}
+	code = strings.Join(strings.Fields(code), "")
+
+	digits := account.DigitCount()
+	if len(code) != digits {
+		return fmt.Errorf("%w: expected a %d-digit code, got '%s'", errUsage, digits, code)
+	}
+
+	if account.Type == "HOTP" {
+		counter, ok, err := otp.VerifyHOTP(secret, code, uint64(account.Counter), verifyLookAhead, digits, account.Algorithm)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return fmt.Errorf("%w: no match for counters %d to %d of '%s'",
+				errCodeMismatch, account.Counter, account.Counter+int64(verifyLookAhead), selection.Label(account))
+		}
+
+		logging.Successf("Code matches the extracted secret of %s (counter %d)", selection.Label(account), counter)
+		if used := int64(counter) - account.Counter; used > 0 {
+			logging.Infof("The phone generated %d code(s) since the export: import the account with counter %d", used, counter+1)
+		}
+		return nil
+	}
+
+	offset, ok, err := otp.VerifyTOTP(secret, code, time.Now(), verifyWindow, digits, account.Algorithm)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("%w: no match within ±%d time steps for '%s' (check the account and this computer's clock)",
+			errCodeMismatch, verifyWindow, selection.Label(account))
+	}
+
+	logging.Successf("Code matches the extracted secret of %s", selection.Label(account))
+	drift := time.Duration(offset) * otp.Period
+	switch {
+	case offset < 0:
+		logging.Infof("Clock drift: the phone is about %s behind this computer (or the code changed while typing it)", -drift)
+	case offset > 0:
+		logging.Infof("Clock drift: the phone is about %s ahead of this computer", drift)
+	default:
+		logging.Infof("Clock drift: none detected (less than %s)", otp.Period)
+	}
+	return nil
+}
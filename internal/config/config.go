@@ -0,0 +1,144 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	AppName   = "gauth-extractor"
+	EnvPrefix = "GAUTH_"
+	EnvConfig = EnvPrefix + "CONFIG"
+)
+
+var ErrInvalidConfig = errors.New("invalid configuration")
+
+// File holds the settings read from a YAML configuration file, flattened to
+// dotted keys: top-level scalars such as "qrimage" or "masking", and
+// "<command>.<flag>" for the flags of a subcommand, e.g. "json.file".
+type File struct {
+	Path   string
+	Values map[string][]string
+}
+
+// DefaultPath returns the configuration file location: $GAUTH_CONFIG, or
+// config.yaml in the gauth-extractor directory of $XDG_CONFIG_HOME (falling
+// back to the platform's user configuration directory).
+func DefaultPath() (string, error) {
+	if path := os.Getenv(EnvConfig); path != "" {
+		return path, nil
+	}
+
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		var err error
+		dir, err = os.UserConfigDir()
+		if err != nil {
+			return "", err
+		}
+	}
+
+	path := filepath.Join(dir, AppName, "config.yaml")
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		if alt := filepath.Join(dir, AppName, "config.yml"); fileExists(alt) {
+			return alt, nil
+		}
+	}
+	return path, nil
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// Load reads the configuration file at path. A missing file is only an error
+// when it was asked for explicitly.
+func Load(path string, explicit bool) (*File, error) {
+	file := &File{Path: path, Values: make(map[string][]string)}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) && !explicit {
+			return file, nil
+		}
+		return nil, fmt.Errorf("failed to read config file '%s': %w", path, err)
+	}
+
+	var root map[string]any
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, fmt.Errorf("%w: '%s': %w", ErrInvalidConfig, path, err)
+	}
+
+	if err := flatten("", root, file.Values); err != nil {
+		return nil, fmt.Errorf("%w: '%s': %w", ErrInvalidConfig, path, err)
+	}
+
+	return file, nil
+}
+
+func flatten(prefix string, node map[string]any, out map[string][]string) error {
+	for key, value := range node {
+		full := key
+		if prefix != "" {
+			full = prefix + "." + key
+		}
+
+		switch v := value.(type) {
+		case map[string]any:
+			if prefix != "" {
+				return fmt.Errorf("section '%s' is nested too deeply", full)
+			}
+			if err := flatten(full, v, out); err != nil {
+				return err
+			}
+		case []any:
+			values := make([]string, 0, len(v))
+			for _, item := range v {
+				values = append(values, fmt.Sprint(item))
+			}
+			out[full] = values
+		case nil:
+		default:
+			out[full] = []string{fmt.Sprint(v)}
+		}
+	}
+	return nil
+}
+
+func (f *File) Keys() []string {
+	keys := make([]string, 0, len(f.Values))
+	for key := range f.Values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// EnvName returns the environment variable for a configuration key:
+// "json.file" is GAUTH_JSON_FILE and "error-format" is GAUTH_ERROR_FORMAT.
+func EnvName(key string) string {
+	return EnvPrefix + strings.ToUpper(strings.NewReplacer(".", "_", "-", "_").Replace(key))
+}
+
+// Lookup returns the value of a key from the environment, which takes
+// precedence, or from the file, along with a description of where it came
+// from. List values may be given in the environment separated by commas.
+func (f *File) Lookup(key string) ([]string, string, bool) {
+	env := EnvName(key)
+	if value, ok := os.LookupEnv(env); ok {
+		return []string{value}, "env " + env, true
+	}
+
+	if values, ok := f.Values[key]; ok {
+		return values, "config " + f.Path, true
+	}
+
+	return nil, "", false
+}
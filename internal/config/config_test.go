@@ -0,0 +1,89 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	data := `masking: full
+file-mode: "0600"
+strict: true
+json:
+  file: backup.json
+`
+	if err := os.WriteFile(path, []byte(data), 0600); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	file, err := Load(path, true)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := map[string][]string{
+		"masking":   {"full"},
+		"file-mode": {"0600"},
+		"strict":    {"true"},
+		"json.file": {"backup.json"},
+	}
+	if !reflect.DeepEqual(file.Values, expected) {
+		t.Errorf("Expected %v, got %v", expected, file.Values)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	dir := t.TempDir()
+
+	missing := filepath.Join(dir, "missing.yaml")
+	if _, err := Load(missing, false); err != nil {
+		t.Errorf("Expected missing default config to be ignored, got %v", err)
+	}
+	if _, err := Load(missing, true); err == nil {
+		t.Errorf("Expected error for missing explicit config")
+	}
+
+	nested := filepath.Join(dir, "nested.yaml")
+	if err := os.WriteFile(nested, []byte("json:\n  file:\n    name: x\n"), 0600); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	if _, err := Load(nested, true); !errors.Is(err, ErrInvalidConfig) {
+		t.Errorf("Expected ErrInvalidConfig, got %v", err)
+	}
+}
+
+func TestLookup(t *testing.T) {
+	file := &File{Path: "config.yaml", Values: map[string][]string{"json.file": {"from-file.json"}}}
+
+	values, source, ok := file.Lookup("json.file")
+	if !ok || values[0] != "from-file.json" || source != "config config.yaml" {
+		t.Errorf("Expected value from file, got %v from %q", values, source)
+	}
+
+	t.Setenv("GAUTH_JSON_FILE", "from-env.json")
+	values, source, ok = file.Lookup("json.file")
+	if !ok || values[0] != "from-env.json" || source != "env GAUTH_JSON_FILE" {
+		t.Errorf("Expected value from environment, got %v from %q", values, source)
+	}
+
+	if _, _, ok := file.Lookup("masking"); ok {
+		t.Errorf("Expected no value for unset key")
+	}
+}
+
+func TestEnvName(t *testing.T) {
+	tests := map[string]string{
+		"masking":      "GAUTH_MASKING",
+		"error-format": "GAUTH_ERROR_FORMAT",
+		"view.show-qr": "GAUTH_VIEW_SHOW_QR",
+	}
+	for key, expected := range tests {
+		if got := EnvName(key); got != expected {
+			t.Errorf("Expected %s for %s, got %s", expected, key, got)
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package decoder
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/Zaphkiel-Ivanovna/GoogleAuthExtractor/internal/proto"
+)
+
+// DigitCount returns the number of digits of the generated codes. Exports
+// that leave it unspecified use the default of 6.
+func (a Account) DigitCount() int {
+	switch a.Digits {
+	case proto.MigrationPayload_SEVEN.String():
+		return 7
+	case proto.MigrationPayload_EIGHT.String():
+		return 8
+	}
+	return 6
+}
+
+// DigitsName returns the value of Account.Digits for a number of digits.
+func DigitsName(digits int) (string, error) {
+	switch digits {
+	case 6:
+		return proto.MigrationPayload_SIX.String(), nil
+	case 7:
+		return proto.MigrationPayload_SEVEN.String(), nil
+	case 8:
+		return proto.MigrationPayload_EIGHT.String(), nil
+	}
+	return "", fmt.Errorf("unsupported number of digits %d: expected 6, 7 or 8", digits)
+}
+
+// AlgorithmName returns the value of Account.Algorithm for a name such as
+// "sha256", in any case.
+func AlgorithmName(name string) (string, error) {
+	upper := strings.ToUpper(name)
+	if value, ok := proto.MigrationPayload_Algorithm_value[upper]; ok && value != 0 {
+		return upper, nil
+	}
+	return "", fmt.Errorf("unsupported algorithm '%s': expected sha1, sha256, sha512 or md5", name)
+}
+
+// AlgorithmOrDefault returns the HMAC algorithm, with unspecified meaning
+// the default SHA1.
+func (a Account) AlgorithmOrDefault() string {
+	if _, err := AlgorithmName(a.Algorithm); err != nil {
+		return proto.MigrationPayload_SHA1.String()
+	}
+	return a.Algorithm
+}
+
+// SecretBytes returns the raw shared secret. For hardened accounts this is
+// the locked buffer itself, which must not be modified or kept.
+func (a Account) SecretBytes() ([]byte, error) {
+	if a.secret != nil {
+		return a.secret.Bytes(), nil
+	}
+	secret, err := base64.StdEncoding.DecodeString(a.Secret)
+	if err != nil {
+		return nil, fmt.Errorf("%w: secret of '%s': %w", ErrInvalidBase64, a.Name, err)
+	}
+	return secret, nil
+}
+
+// Fingerprints are the first 80 bits of an HMAC-SHA256 of the secret, in
+// four groups of four base32 characters.
+const fingerprintBytes = 10
+
+// KeyedFingerprint returns the fingerprint of the account's secret under
+// key. Only fingerprints made with the same key can be compared, and without
+// the key a fingerprint cannot be checked against a guessed secret. An empty
+// key protects nothing: the fingerprint is then a plain hash of the secret.
+func (a Account) KeyedFingerprint(key []byte) (string, error) {
+	secret, err := a.SecretBytes()
+	if err != nil {
+		return "", err
+	}
+
+	mac := hmac.New(sha256.New, key)
+	mac.Write(secret)
+	encoded := base32.StdEncoding.EncodeToString(mac.Sum(nil)[:fingerprintBytes])
+
+	groups := make([]string, 0, len(encoded)/4)
+	for i := 0; i < len(encoded); i += 4 {
+		groups = append(groups, encoded[i:i+4])
+	}
+	return strings.Join(groups, "-"), nil
+}
+
+// NewFingerprintKey returns a random key, for fingerprints that are only
+// compared within one run.
+func NewFingerprintKey() ([]byte, error) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("failed to generate fingerprint key: %w", err)
+	}
+	return key, nil
+}
+
+// SetFingerprints sets the Fingerprint of every account using key.
+func SetFingerprints(accounts []Account, key []byte) error {
+	for i := range accounts {
+		fingerprint, err := accounts[i].KeyedFingerprint(key)
+		if err != nil {
+			return err
+		}
+		accounts[i].Fingerprint = fingerprint
+	}
+	return nil
+}
+
+// Base32 returns the secret in base32, as other authenticator apps expect.
+func (a Account) Base32() string {
+	if a.secret != nil {
+		return toBase32(a.secret.Bytes())
+	}
+	return a.TOTPSecret
+}
+
+// Base64 returns the secret in base64.
+func (a Account) Base64() string {
+	if a.secret != nil {
+		return base64.StdEncoding.EncodeToString(a.secret.Bytes())
+	}
+	return a.Secret
+}
+
+// Destroy wipes the secret of a hardened account once it is no longer
+// needed. Other accounts are left as they are.
+func (a Account) Destroy() {
+	a.secret.Destroy()
+}
+
+// MarshalJSON fills in the secrets of hardened accounts, which only hold
+// them in a buffer.
+func (a Account) MarshalJSON() ([]byte, error) {
+	type account Account
+	out := account(a)
+	out.Secret, out.TOTPSecret = a.Base64(), a.Base32()
+	return json.Marshal(out)
+}
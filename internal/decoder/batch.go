@@ -0,0 +1,101 @@
+package decoder
+
+import (
+	"sort"
+
+	"github.com/Zaphkiel-Ivanovna/GoogleAuthExtractor/internal/logging"
+)
+
+// DecodeExportURIs decodes the QR codes of one or more exports. Google
+// Authenticator splits large exports into batches sharing a batch ID; the
+// batches are put back in order, repeated scans of the same batch are
+// ignored and missing batches are reported.
+func DecodeExportURIs(uris []string, opts Options) ([]Account, error) {
+	exports, err := DecodeExports(uris, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var accounts []Account
+	for _, export := range exports {
+		accounts = append(accounts, export.Accounts...)
+	}
+	return accounts, nil
+}
+
+// Export holds the accounts of all the QR codes of one export.
+type Export struct {
+	BatchID  int32
+	Accounts []Account
+}
+
+// DecodeExports is DecodeExportURIs keeping the exports apart, in the order
+// they first appear in uris.
+func DecodeExports(uris []string, opts Options) ([]Export, error) {
+
+	batches := make(map[int32]map[int32]*Payload)
+	var batchIDs []int32
+
+	seen := make(map[string]bool)
+	for _, uri := range uris {
+		if seen[uri] {
+			continue
+		}
+		seen[uri] = true
+
+		payload, err := parseExportURI(uri, opts.Hardened)
+		if err != nil {
+			return nil, err
+		}
+		if err := checkVersion(payload, opts); err != nil {
+			return nil, err
+		}
+
+		if batches[payload.BatchID] == nil {
+			batches[payload.BatchID] = make(map[int32]*Payload)
+			batchIDs = append(batchIDs, payload.BatchID)
+		}
+		if _, ok := batches[payload.BatchID][payload.BatchIndex]; ok {
+			logging.Debugf("Ignoring repeated QR code %d of export %d", payload.BatchIndex+1, payload.BatchID)
+			continue
+		}
+		logging.Debugf("Decoded QR code %d of %d of export %d (%d accounts)",
+			payload.BatchIndex+1, max(payload.BatchSize, 1), payload.BatchID, len(payload.Accounts))
+		batches[payload.BatchID][payload.BatchIndex] = payload
+	}
+
+	var exports []Export
+	for _, id := range batchIDs {
+		parts := batches[id]
+
+		indexes := make([]int32, 0, len(parts))
+		for index := range parts {
+			indexes = append(indexes, index)
+		}
+		sort.Slice(indexes, func(i, j int) bool { return indexes[i] < indexes[j] })
+
+		export := Export{BatchID: id}
+		var size int32
+		for _, index := range indexes {
+			export.Accounts = append(export.Accounts, parts[index].Accounts...)
+			size = max(size, parts[index].BatchSize)
+		}
+
+		if missing := missingBatches(parts, size); len(missing) > 0 {
+			logging.Warnf("Export %d is incomplete: missing QR code(s) %v of %d.", id, missing, size)
+		}
+		exports = append(exports, export)
+	}
+
+	return exports, nil
+}
+
+func missingBatches(parts map[int32]*Payload, size int32) []int32 {
+	var missing []int32
+	for index := int32(0); index < size; index++ {
+		if _, ok := parts[index]; !ok {
+			missing = append(missing, index+1)
+		}
+	}
+	return missing
+}
@@ -0,0 +1,110 @@
+package decoder
+
+import (
+	"encoding/base64"
+	"net/url"
+	"testing"
+
+	"github.com/Zaphkiel-Ivanovna/GoogleAuthExtractor/internal/proto"
+	pb "google.golang.org/protobuf/proto"
+)
+
+func batchURI(t *testing.T, batchID, index, size int32, names ...string) string {
+	t.Helper()
+
+	payload := &proto.MigrationPayload{
+		Version:    1,
+		BatchId:    batchID,
+		BatchIndex: index,
+		BatchSize:  size,
+	}
+	for _, name := range names {
+		payload.OtpParameters = append(payload.OtpParameters, &proto.MigrationPayload_OtpParameters{
+			Secret:    []byte(name),
+			Name:      name,
+			Algorithm: proto.MigrationPayload_SHA1,
+			Digits:    proto.MigrationPayload_SIX,
+			Type:      proto.MigrationPayload_TOTP,
+		})
+	}
+
+	data, err := pb.Marshal(payload)
+	if err != nil {
+		t.Fatalf("Failed to marshal payload: %v", err)
+	}
+	return "otpauth-migration://offline?data=" + url.QueryEscape(base64.StdEncoding.EncodeToString(data))
+}
+
+func TestDecodeExportURIs(t *testing.T) {
+	first := batchURI(t, 7, 0, 3, "a1", "a2")
+	second := batchURI(t, 7, 1, 3, "a3")
+	third := batchURI(t, 7, 2, 3, "a4")
+	other := batchURI(t, 9, 0, 1, "b1")
+
+	tests := []struct {
+		name     string
+		uris     []string
+		expected []string
+	}{
+		{
+			name:     "Batches in order",
+			uris:     []string{first, second, third},
+			expected: []string{"a1", "a2", "a3", "a4"},
+		},
+		{
+			name:     "Batches out of order and repeated",
+			uris:     []string{third, first, third, second, first},
+			expected: []string{"a1", "a2", "a3", "a4"},
+		},
+		{
+			name:     "Missing batch",
+			uris:     []string{third, first},
+			expected: []string{"a1", "a2", "a4"},
+		},
+		{
+			name:     "Several exports",
+			uris:     []string{other, second, first, third},
+			expected: []string{"b1", "a1", "a2", "a3", "a4"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			accounts, err := DecodeExportURIs(tt.uris, Options{})
+			if err != nil {
+				t.Fatalf("Did not expect error but got: %v", err)
+			}
+
+			if len(accounts) != len(tt.expected) {
+				t.Fatalf("Expected %d accounts, got %d", len(tt.expected), len(accounts))
+			}
+			for i, name := range tt.expected {
+				if accounts[i].Name != name {
+					t.Errorf("Expected account %d to be '%s', got '%s'", i, name, accounts[i].Name)
+				}
+			}
+		})
+	}
+}
+
+func TestDecodeExportURIsInvalid(t *testing.T) {
+	_, err := DecodeExportURIs([]string{batchURI(t, 1, 0, 1, "a"), "https://example.com"}, Options{})
+	if err == nil {
+		t.Errorf("Expected error for an invalid URI but got nil")
+	}
+}
+
+func TestDecodeExports(t *testing.T) {
+	uris := []string{batchURI(t, 9, 1, 2, "b2"), batchURI(t, 7, 0, 1, "a1"), batchURI(t, 9, 0, 2, "b1")}
+
+	exports, err := DecodeExports(uris, Options{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(exports) != 2 || exports[0].BatchID != 9 || exports[1].BatchID != 7 {
+		t.Fatalf("Expected exports 9 and 7, got %+v", exports)
+	}
+	if len(exports[0].Accounts) != 2 || exports[0].Accounts[0].Name != "b1" || exports[1].Accounts[0].Name != "a1" {
+		t.Errorf("Expected accounts b1, b2 and a1, got %+v", exports)
+	}
+}
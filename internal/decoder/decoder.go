@@ -3,14 +3,28 @@ package decoder
 import (
 	"encoding/base32"
 	"encoding/base64"
+	"errors"
 	"fmt"
 	"net/url"
 	"strings"
 
+	"github.com/Zaphkiel-Ivanovna/GoogleAuthExtractor/internal/logging"
 	"github.com/Zaphkiel-Ivanovna/GoogleAuthExtractor/internal/proto"
+	"github.com/Zaphkiel-Ivanovna/GoogleAuthExtractor/internal/secmem"
 	pb "google.golang.org/protobuf/proto"
 )
 
+const SupportedVersion = 1
+
+var (
+	ErrInvalidURI         = errors.New("invalid URI format")
+	ErrInvalidScheme      = errors.New("invalid URI scheme")
+	ErrMissingData        = errors.New("missing 'data' parameter in URI")
+	ErrInvalidBase64      = errors.New("failed to base64-decode data")
+	ErrInvalidProtobuf    = errors.New("failed to decode protobuf data")
+	ErrUnsupportedVersion = errors.New("unsupported payload version")
+)
+
 type Account struct {
 	Name       string `json:"name"`
 	Issuer     string `json:"issuer,omitempty"`
@@ -20,55 +34,89 @@ type Account struct {
 	Algorithm  string `json:"algorithm"`
 	Digits     string `json:"digits"`
 	Counter    int64  `json:"counter,omitempty"`
+	UniqueID   string `json:"uniqueId,omitempty"`
+	// Fingerprint identifies the secret without revealing it. It is set by
+	// SetFingerprints, and left empty when no fingerprint key is configured.
+	Fingerprint string `json:"fingerprint,omitempty"`
+
+	// secret holds the secret instead of Secret and TOTPSecret when decoded
+	// with Options.Hardened.
+	secret *secmem.Buffer
+}
+
+type Payload struct {
+	Accounts   []Account
+	Version    int32
+	BatchSize  int32
+	BatchIndex int32
+	BatchID    int32
+}
+
+type Options struct {
+	// Strict rejects payloads with an unexpected version instead of warning.
+	Strict bool
+	// Hardened keeps secrets in locked buffers instead of strings, see
+	// package secmem. They are only turned into strings for output.
+	Hardened bool
 }
 
 func DecodeExportURI(uri string) ([]Account, error) {
+	return DecodeExportURIs([]string{uri}, Options{})
+}
+
+func ParseExportURI(uri string) (*Payload, error) {
+	return parseExportURI(uri, false)
+}
+
+func parseExportURI(uri string, hardened bool) (*Payload, error) {
 
 	parsedURL, err := url.Parse(uri)
 	if err != nil {
-		return nil, fmt.Errorf("invalid URI format: %w", err)
+		return nil, fmt.Errorf("%w: %w", ErrInvalidURI, err)
 	}
 
 	if !strings.HasPrefix(parsedURL.Scheme, "otpauth-migration") {
-		return nil, fmt.Errorf("invalid URI scheme: expected 'otpauth-migration', got '%s'", parsedURL.Scheme)
+		return nil, fmt.Errorf("%w: expected 'otpauth-migration', got '%s'", ErrInvalidScheme, parsedURL.Scheme)
 	}
 
 	queryParams := parsedURL.Query()
 	dataParam := queryParams.Get("data")
 	if dataParam == "" {
-		return nil, fmt.Errorf("missing 'data' parameter in URI")
+		return nil, ErrMissingData
 	}
 
 	decodedData, err := url.QueryUnescape(dataParam)
 	if err != nil {
-		return nil, fmt.Errorf("failed to URL-decode data parameter: %w", err)
+		return nil, fmt.Errorf("%w: failed to URL-decode data parameter: %w", ErrInvalidURI, err)
 	}
 
 	rawData, err := base64.StdEncoding.DecodeString(decodedData)
 	if err != nil {
-		return nil, fmt.Errorf("failed to base64-decode data: %w", err)
+		return nil, fmt.Errorf("%w: %w", ErrInvalidBase64, err)
 	}
 
 	payload := &proto.MigrationPayload{}
 	err = pb.Unmarshal(rawData, payload)
+	secmem.Wipe(rawData)
 	if err != nil {
-		return nil, fmt.Errorf("failed to decode protobuf data: %w", err)
-	}
-
-	if payload.Version != 1 {
-		fmt.Printf("Warning: Expected payload version 1, but got %d. This might cause issues.\n", payload.Version)
+		return nil, fmt.Errorf("%w: %w", ErrInvalidProtobuf, err)
 	}
 
 	accounts := make([]Account, 0, len(payload.OtpParameters))
 	for _, otpParams := range payload.OtpParameters {
 		account := Account{
-			Name:       otpParams.Name,
-			Issuer:     otpParams.Issuer,
-			Secret:     base64.StdEncoding.EncodeToString(otpParams.Secret),
-			TOTPSecret: toBase32(otpParams.Secret),
-			Type:       otpParams.Type.String(),
-			Algorithm:  otpParams.Algorithm.String(),
-			Digits:     otpParams.Digits.String(),
+			Name:      otpParams.Name,
+			Issuer:    otpParams.Issuer,
+			Type:      otpParams.Type.String(),
+			Algorithm: otpParams.Algorithm.String(),
+			Digits:    otpParams.Digits.String(),
+			UniqueID:  otpParams.UniqueId,
+		}
+		if hardened {
+			account.secret = secmem.FromBytes(otpParams.Secret)
+		} else {
+			account.Secret = base64.StdEncoding.EncodeToString(otpParams.Secret)
+			account.TOTPSecret = toBase32(otpParams.Secret)
 		}
 
 		if otpParams.Type == proto.MigrationPayload_HOTP {
@@ -78,7 +126,26 @@ func DecodeExportURI(uri string) ([]Account, error) {
 		accounts = append(accounts, account)
 	}
 
-	return accounts, nil
+	return &Payload{
+		Accounts:   accounts,
+		Version:    payload.Version,
+		BatchSize:  payload.BatchSize,
+		BatchIndex: payload.BatchIndex,
+		BatchID:    payload.BatchId,
+	}, nil
+}
+
+func checkVersion(payload *Payload, opts Options) error {
+	if payload.Version == SupportedVersion {
+		return nil
+	}
+
+	if opts.Strict {
+		return fmt.Errorf("%w: expected %d, got %d", ErrUnsupportedVersion, SupportedVersion, payload.Version)
+	}
+
+	logging.Warnf("Expected payload version %d, but got %d. This might cause issues.", SupportedVersion, payload.Version)
+	return nil
 }
 
 func toBase32(data []byte) string {
@@ -1,7 +1,13 @@
 package decoder
 
 import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
 	"testing"
+
+	"github.com/Zaphkiel-Ivanovna/GoogleAuthExtractor/internal/proto"
+	pb "google.golang.org/protobuf/proto"
 )
 
 func TestDecodeExportURI(t *testing.T) {
@@ -112,3 +118,87 @@ func TestInvalidURIs(t *testing.T) {
 		}
 	}
 }
+
+func TestDecodeErrors(t *testing.T) {
+	futureVersion, err := pb.Marshal(&proto.MigrationPayload{Version: 2})
+	if err != nil {
+		t.Fatalf("Failed to marshal payload: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		uri      string
+		strict   bool
+		expected error
+	}{
+		{"Invalid scheme", "https://example.com", false, ErrInvalidScheme},
+		{"Missing data", "otpauth-migration://offline", false, ErrMissingData},
+		{"Invalid base64", "otpauth-migration://offline?data=not-base64", false, ErrInvalidBase64},
+		{"Invalid protobuf", "otpauth-migration://offline?data=" + base64.StdEncoding.EncodeToString([]byte{0xff, 0xff}), false, ErrInvalidProtobuf},
+		{"Unsupported version", "otpauth-migration://offline?data=" + base64.StdEncoding.EncodeToString(futureVersion), true, ErrUnsupportedVersion},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := DecodeExportURIs([]string{tt.uri}, Options{Strict: tt.strict})
+			if !errors.Is(err, tt.expected) {
+				t.Errorf("Expected error matching '%v', got: %v", tt.expected, err)
+			}
+		})
+	}
+
+	if _, err := DecodeExportURIs([]string{"otpauth-migration://offline?data=" + base64.StdEncoding.EncodeToString(futureVersion)}, Options{}); err != nil {
+		t.Errorf("Expected an unexpected version to only warn outside strict mode, got: %v", err)
+	}
+}
+
+func TestKeyedFingerprint(t *testing.T) {
+	account := Account{Name: "alice", Secret: "SGVsbG8h3q2+7w=="}
+
+	tests := []struct {
+		key      string
+		expected string
+	}{
+		{"", "WUQF-MBU7-53BI-LBYF"},
+		{"team key", "3AIA-5YYS-U3EW-RNOU"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			got, err := account.KeyedFingerprint([]byte(tt.key))
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("Expected %s, got %s", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestHardenedDecoding(t *testing.T) {
+	uri := "otpauth-migration://offline?data=CjoKFD1jwRTgK6xTGKA0gdTWaGMebxmTEg1UT1RQZ2VuZXJhdG9yGg1UT1RQZ2VuZXJhdG9yIAMoAjACEAEYASAAKJ2G1g0%3D"
+
+	plain, err := DecodeExportURIs([]string{uri}, Options{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	hardened, err := DecodeExportURIs([]string{uri}, Options{Hardened: true})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	account := hardened[0]
+	if account.Secret != "" || account.TOTPSecret != "" {
+		t.Errorf("Expected no secret strings in a hardened account, got %q and %q", account.Secret, account.TOTPSecret)
+	}
+	if account.Base32() != plain[0].TOTPSecret || account.Base64() != plain[0].Secret {
+		t.Errorf("Expected the secret %s, got %s", plain[0].TOTPSecret, account.Base32())
+	}
+
+	plainJSON, _ := json.Marshal(plain[0])
+	hardenedJSON, err := json.Marshal(account)
+	if err != nil || string(hardenedJSON) != string(plainJSON) {
+		t.Errorf("Expected JSON %s, got %s (%v)", plainJSON, hardenedJSON, err)
+	}
+}
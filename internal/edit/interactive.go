@@ -0,0 +1,173 @@
+package edit
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/Zaphkiel-Ivanovna/GoogleAuthExtractor/internal/decoder"
+	"github.com/Zaphkiel-Ivanovna/GoogleAuthExtractor/internal/selection"
+	"github.com/fatih/color"
+)
+
+var (
+	ErrEditAborted = errors.New("editing aborted")
+	ErrNothingLeft = errors.New("every account was dropped while editing")
+)
+
+type editor struct {
+	accounts []decoder.Account
+	dropped  []bool
+	scanner  *bufio.Scanner
+	out      io.Writer
+}
+
+// Interactive lists the accounts on out and reads editing commands from in
+// until the user confirms with an empty line:
+//
+//	3      edit the name, issuer, digits and algorithm of account 3
+//	d 2-4  drop accounts 2 to 4 (again to keep them)
+//	s      split "Issuer:Name" labels of all accounts
+//	q      abort without exporting anything
+func Interactive(accounts []decoder.Account, in *bufio.Scanner, out io.Writer) ([]decoder.Account, error) {
+	e := &editor{
+		accounts: append([]decoder.Account(nil), accounts...),
+		dropped:  make([]bool, len(accounts)),
+		scanner:  in,
+		out:      out,
+	}
+
+	for {
+		e.list()
+
+		fmt.Fprint(out, "\nEdit # (d N=drop, s=split labels, q=quit, Enter=done): ")
+		answer, ok := e.readLine()
+		if !ok {
+			return nil, ErrEditAborted
+		}
+
+		command, arg, _ := strings.Cut(answer, " ")
+		switch strings.ToLower(command) {
+		case "":
+			var result []decoder.Account
+			for i, account := range e.accounts {
+				if !e.dropped[i] {
+					result = append(result, account)
+				}
+			}
+			return result, nil
+		case "q", "quit":
+			return nil, ErrEditAborted
+		case "s", "split":
+			for i := range e.accounts {
+				e.accounts[i] = SplitLabel(e.accounts[i])
+			}
+		case "d", "drop":
+			indexes, err := selection.ParseIndexes(arg, len(e.accounts))
+			if err != nil || indexes == nil {
+				e.warn("expected the numbers of the accounts to drop, e.g. d 2-4")
+				continue
+			}
+			for i := range indexes {
+				e.dropped[i] = !e.dropped[i]
+			}
+		default:
+			index, err := strconv.Atoi(command)
+			if err != nil || index < 1 || index > len(e.accounts) {
+				e.warn(fmt.Sprintf("unknown command '%s'", answer))
+				continue
+			}
+			if !e.edit(index - 1) {
+				return nil, ErrEditAborted
+			}
+		}
+	}
+}
+
+func (e *editor) list() {
+	cyan := color.New(color.FgCyan, color.Bold).SprintFunc()
+	faint := color.New(color.Faint, color.CrossedOut).SprintFunc()
+
+	fmt.Fprintf(e.out, "\n%s\n", cyan("Edit accounts"))
+	for i, account := range e.accounts {
+		line := fmt.Sprintf("%2d. %s  [%s, %d digits, %s]", i+1, selection.Label(account),
+			account.Type, account.DigitCount(), account.AlgorithmOrDefault())
+		if e.dropped[i] {
+			line = faint(line) + " (dropped)"
+		}
+		fmt.Fprintf(e.out, "  %s\n", line)
+	}
+}
+
+// edit prompts for each field of an account, keeping the current value on
+// an empty answer. It returns false when the input ends.
+func (e *editor) edit(i int) bool {
+	account := e.accounts[i]
+
+	for _, field := range []struct {
+		label   string
+		current func() string
+		set     func(string) error
+	}{
+		{"Name", func() string { return account.Name }, func(v string) error {
+			account.Name = v
+			return nil
+		}},
+		{"Issuer (- to clear)", func() string { return account.Issuer }, func(v string) error {
+			if v == "-" {
+				v = ""
+			}
+			account.Issuer = v
+			return nil
+		}},
+		{"Digits", func() string { return strconv.Itoa(account.DigitCount()) }, func(v string) error {
+			digits, err := strconv.Atoi(v)
+			if err != nil {
+				return fmt.Errorf("invalid number of digits '%s'", v)
+			}
+			account.Digits, err = decoder.DigitsName(digits)
+			return err
+		}},
+		{"Algorithm", func() string { return account.AlgorithmOrDefault() }, func(v string) error {
+			var err error
+			account.Algorithm, err = decoder.AlgorithmName(v)
+			return err
+		}},
+	} {
+		for {
+			fmt.Fprintf(e.out, "  %s [%s]: ", field.label, field.current())
+			answer, ok := e.readLine()
+			if !ok {
+				return false
+			}
+			if answer == "" {
+				break
+			}
+
+			saved := account
+			if err := field.set(answer); err != nil {
+				account = saved
+				e.warn(err.Error())
+				continue
+			}
+			break
+		}
+	}
+
+	e.accounts[i] = account
+	return true
+}
+
+func (e *editor) readLine() (string, bool) {
+	if !e.scanner.Scan() {
+		return "", false
+	}
+	return strings.TrimSpace(e.scanner.Text()), true
+}
+
+func (e *editor) warn(message string) {
+	fmt.Fprintf(e.out, "%s\n", color.YellowString("Warning: %s", message))
+}
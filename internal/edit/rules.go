@@ -0,0 +1,254 @@
+package edit
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/Zaphkiel-Ivanovna/GoogleAuthExtractor/internal/decoder"
+	"github.com/Zaphkiel-Ivanovna/GoogleAuthExtractor/internal/selection"
+	"gopkg.in/yaml.v3"
+)
+
+var ErrInvalidRules = errors.New("invalid edit rules")
+
+// Rules is a scripted edit, read from a YAML file:
+//
+//	split-labels: true
+//	rules:
+//	  - match: {issuer: "", name: "/@corp\\.com$/"}
+//	    issuer: Corp
+//	  - match: {name: "/^(.+)@corp\\.com$/"}
+//	    name: $1
+//	  - match: {issuer: "/^github(\\.com)?$/"}
+//	    issuer: GitHub
+//	  - match: {issuer: Symantec VIP}
+//	    digits: 8
+//	  - match: {name: "test*"}
+//	    drop: true
+//
+// Every rule whose match applies changes the account in turn, so later rules
+// see the result of earlier ones.
+type Rules struct {
+	SplitLabels bool   `yaml:"split-labels"`
+	Rules       []Rule `yaml:"rules"`
+}
+
+type Rule struct {
+	Match Match `yaml:"match"`
+
+	// Name and Issuer replace the account's values. "$1" and "${group}" refer
+	// to submatches of a regular expression in the match: Name uses the name
+	// pattern, Issuer the issuer pattern, or the name pattern when the match
+	// has no issuer pattern.
+	Name      *string `yaml:"name"`
+	Issuer    *string `yaml:"issuer"`
+	Digits    int     `yaml:"digits"`
+	Algorithm string  `yaml:"algorithm"`
+	// SplitLabel moves an "Issuer:" prefix of the name to the issuer.
+	SplitLabel bool `yaml:"split-label"`
+	Drop       bool `yaml:"drop"`
+
+	issuer, name *selection.Pattern
+	digits       string
+	algorithm    string
+}
+
+// Match selects the accounts a rule applies to. Issuer and Name are patterns
+// as accepted by --issuer and --name; an empty issuer pattern only matches
+// accounts without an issuer. A rule without a match applies to every
+// account.
+type Match struct {
+	Issuer    *string `yaml:"issuer"`
+	Name      *string `yaml:"name"`
+	Type      string  `yaml:"type"`
+	Algorithm string  `yaml:"algorithm"`
+}
+
+func LoadRules(path string) (*Rules, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read edit rules '%s': %w", path, err)
+	}
+
+	rules, err := ParseRules(data)
+	if err != nil {
+		return nil, fmt.Errorf("'%s': %w", path, err)
+	}
+	return rules, nil
+}
+
+func ParseRules(data []byte) (*Rules, error) {
+	var rules Rules
+
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&rules); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidRules, err)
+	}
+
+	for i := range rules.Rules {
+		if err := rules.Rules[i].compile(); err != nil {
+			return nil, fmt.Errorf("%w: rule %d: %w", ErrInvalidRules, i+1, err)
+		}
+	}
+
+	return &rules, nil
+}
+
+func (r *Rule) compile() error {
+	var err error
+	if r.Match.Issuer != nil {
+		if r.issuer, err = selection.ParsePattern(*r.Match.Issuer); err != nil {
+			return err
+		}
+	}
+	if r.Match.Name != nil {
+		if r.name, err = selection.ParsePattern(*r.Match.Name); err != nil {
+			return err
+		}
+	}
+
+	switch t := strings.ToUpper(r.Match.Type); t {
+	case "", "TOTP", "HOTP":
+		r.Match.Type = t
+	default:
+		return fmt.Errorf("invalid type '%s': expected totp or hotp", r.Match.Type)
+	}
+
+	if r.Match.Algorithm != "" {
+		if r.Match.Algorithm, err = decoder.AlgorithmName(r.Match.Algorithm); err != nil {
+			return err
+		}
+	}
+	if r.Digits != 0 {
+		if r.digits, err = decoder.DigitsName(r.Digits); err != nil {
+			return err
+		}
+	}
+	if r.Algorithm != "" {
+		if r.algorithm, err = decoder.AlgorithmName(r.Algorithm); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (r *Rule) matches(account decoder.Account) bool {
+	if r.issuer != nil && !r.issuer.Match(account.Issuer) {
+		return false
+	}
+	if r.name != nil && !r.name.Match(account.Name) {
+		return false
+	}
+	if r.Match.Type != "" && account.Type != r.Match.Type {
+		return false
+	}
+	if r.Match.Algorithm != "" && account.AlgorithmOrDefault() != r.Match.Algorithm {
+		return false
+	}
+	return true
+}
+
+// apply returns the edited account, or false when the rule drops it.
+func (r *Rule) apply(account decoder.Account) (decoder.Account, bool) {
+	if r.Drop {
+		return account, false
+	}
+
+	original := account
+	if r.SplitLabel {
+		account = SplitLabel(account)
+	}
+	if r.Name != nil {
+		account.Name = strings.TrimSpace(r.expand(r.name, *r.Name, original.Name))
+	}
+	if r.Issuer != nil {
+		if r.issuer != nil {
+			account.Issuer = strings.TrimSpace(r.expand(r.issuer, *r.Issuer, original.Issuer))
+		} else {
+			account.Issuer = strings.TrimSpace(r.expand(r.name, *r.Issuer, original.Name))
+		}
+	}
+	if r.digits != "" {
+		account.Digits = r.digits
+	}
+	if r.algorithm != "" {
+		account.Algorithm = r.algorithm
+	}
+
+	return account, true
+}
+
+func (r *Rule) expand(pattern *selection.Pattern, template, value string) string {
+	if pattern == nil {
+		return template
+	}
+	return pattern.Expand(template, value)
+}
+
+// Apply runs the rules over the accounts and returns the result, along with
+// the number of accounts that were changed and dropped.
+func (rs *Rules) Apply(accounts []decoder.Account) (result []decoder.Account, changed, dropped int) {
+	for _, account := range accounts {
+		edited, keep := rs.applyOne(account)
+		if !keep {
+			dropped++
+			continue
+		}
+		if edited != account {
+			changed++
+		}
+		result = append(result, edited)
+	}
+	return result, changed, dropped
+}
+
+func (rs *Rules) applyOne(account decoder.Account) (decoder.Account, bool) {
+	if rs.SplitLabels {
+		account = SplitLabel(account)
+	}
+
+	for i := range rs.Rules {
+		rule := &rs.Rules[i]
+		if !rule.matches(account) {
+			continue
+		}
+
+		var keep bool
+		if account, keep = rule.apply(account); !keep {
+			return account, false
+		}
+	}
+
+	return account, true
+}
+
+// SplitLabel handles names holding a full "Issuer:Name" label, as some sites
+// put in their QR codes. The prefix becomes the issuer when the account has
+// none, and is dropped when it repeats the issuer.
+func SplitLabel(account decoder.Account) decoder.Account {
+	prefix, name, ok := strings.Cut(account.Name, ":")
+	if !ok {
+		return account
+	}
+
+	prefix, name = strings.TrimSpace(prefix), strings.TrimSpace(name)
+	if prefix == "" || name == "" {
+		return account
+	}
+
+	switch {
+	case account.Issuer == "":
+		account.Issuer = prefix
+	case !strings.EqualFold(account.Issuer, prefix):
+		return account
+	}
+
+	account.Name = name
+	return account
+}
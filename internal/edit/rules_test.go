@@ -0,0 +1,136 @@
+package edit
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Zaphkiel-Ivanovna/GoogleAuthExtractor/internal/decoder"
+)
+
+const testRules = `
+split-labels: true
+rules:
+  - match: {issuer: "", name: "/@(corp)\\.com$/"}
+    issuer: Corp
+  - match: {name: "/^(.+)@corp\\.com$/"}
+    name: $1
+  - match: {issuer: "/^github(\\.com)?$/"}
+    issuer: GitHub
+  - match: {issuer: "Symantec*"}
+    digits: 8
+    algorithm: sha256
+  - match: {name: "test*"}
+    drop: true
+`
+
+func TestRulesApply(t *testing.T) {
+	rules, err := ParseRules([]byte(testRules))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	accounts := []decoder.Account{
+		{Name: "alice@corp.com", Type: "TOTP", Digits: "SIX"},
+		{Name: "github.com:alice", Type: "TOTP", Digits: "SIX"},
+		{Name: "VSST12345", Issuer: "Symantec VIP", Type: "TOTP", Digits: "SIX", Algorithm: "SHA1"},
+		{Name: "test account", Issuer: "Example", Type: "TOTP", Digits: "SIX"},
+		{Name: "bob", Issuer: "Example", Type: "TOTP", Digits: "SIX"},
+	}
+
+	expected := []decoder.Account{
+		{Name: "alice", Issuer: "Corp", Type: "TOTP", Digits: "SIX"},
+		{Name: "alice", Issuer: "GitHub", Type: "TOTP", Digits: "SIX"},
+		{Name: "VSST12345", Issuer: "Symantec VIP", Type: "TOTP", Digits: "EIGHT", Algorithm: "SHA256"},
+		{Name: "bob", Issuer: "Example", Type: "TOTP", Digits: "SIX"},
+	}
+
+	result, changed, dropped := rules.Apply(accounts)
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, result)
+	}
+	if changed != 3 || dropped != 1 {
+		t.Errorf("Expected 3 changed and 1 dropped, got %d and %d", changed, dropped)
+	}
+}
+
+func TestParseRulesErrors(t *testing.T) {
+	tests := map[string]string{
+		"Unknown field":   "rules:\n  - match: {name: x}\n    rename: y\n",
+		"Bad pattern":     "rules:\n  - match: {name: \"/(/\"}\n    drop: true\n",
+		"Bad digits":      "rules:\n  - digits: 5\n",
+		"Bad algorithm":   "rules:\n  - algorithm: sha3\n",
+		"Bad type":        "rules:\n  - match: {type: steam}\n",
+		"Not a rule list": "rules: yes\n",
+	}
+
+	for name, data := range tests {
+		if _, err := ParseRules([]byte(data)); !errors.Is(err, ErrInvalidRules) {
+			t.Errorf("%s: expected ErrInvalidRules, got %v", name, err)
+		}
+	}
+}
+
+func TestSplitLabel(t *testing.T) {
+	tests := []struct {
+		account  decoder.Account
+		expected decoder.Account
+	}{
+		{decoder.Account{Name: "GitHub:alice"}, decoder.Account{Name: "alice", Issuer: "GitHub"}},
+		{decoder.Account{Name: "github: alice", Issuer: "GitHub"}, decoder.Account{Name: "alice", Issuer: "GitHub"}},
+		{decoder.Account{Name: "Other:alice", Issuer: "GitHub"}, decoder.Account{Name: "Other:alice", Issuer: "GitHub"}},
+		{decoder.Account{Name: "alice"}, decoder.Account{Name: "alice"}},
+		{decoder.Account{Name: ":alice"}, decoder.Account{Name: ":alice"}},
+	}
+
+	for _, tt := range tests {
+		if got := SplitLabel(tt.account); got != tt.expected {
+			t.Errorf("Expected %+v, got %+v", tt.expected, got)
+		}
+	}
+}
+
+func TestInteractive(t *testing.T) {
+	accounts := []decoder.Account{
+		{Name: "Example:alice", Type: "TOTP", Digits: "SIX"},
+		{Name: "bob", Type: "TOTP", Digits: "SIX"},
+		{Name: "carol", Type: "TOTP", Digits: "SIX"},
+	}
+
+	input := strings.Join([]string{
+		"s",      // split labels
+		"2",      // edit bob
+		"robert", // name
+		"Corp",   // issuer
+		"9",      // invalid digits, asked again
+		"8",      // digits
+		"",       // keep algorithm
+		"d 3",    // drop carol
+		"",       // done
+	}, "\n") + "\n"
+
+	var out bytes.Buffer
+	result, err := Interactive(accounts, bufio.NewScanner(strings.NewReader(input)), &out)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := []decoder.Account{
+		{Name: "alice", Issuer: "Example", Type: "TOTP", Digits: "SIX"},
+		{Name: "robert", Issuer: "Corp", Type: "TOTP", Digits: "EIGHT"},
+	}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, result)
+	}
+
+	if accounts[1].Name != "bob" {
+		t.Errorf("Expected input accounts to be left unchanged")
+	}
+
+	if _, err := Interactive(accounts, bufio.NewScanner(strings.NewReader("q\n")), &out); !errors.Is(err, ErrEditAborted) {
+		t.Errorf("Expected ErrEditAborted, got %v", err)
+	}
+}
@@ -0,0 +1,359 @@
+package input
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"image"
+	"image/draw"
+	"image/gif"
+	"image/png"
+)
+
+// frameFunc receives every composed frame of an animation, in display order.
+// The image is only valid until the function returns.
+type frameFunc func(index int, frame image.Image) error
+
+// maxAnimationFrames bounds the frames composed and scanned, each of which
+// costs a pass over the canvas. Screen recordings stay far below it.
+const maxAnimationFrames = 3000
+
+// gifFrames composes the frames of a (possibly animated) GIF, honouring each
+// frame's disposal method, and returns the number of frames. Frames are
+// decoded one at a time, so that only the canvas and the current frame are
+// held in memory.
+func gifFrames(data []byte, fn frameFunc) (int, error) {
+	config, err := gif.DecodeConfig(bytes.NewReader(data))
+	if err == nil {
+		err = checkImageSize(config.Width, config.Height)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to decode image (detected: GIF): %w", err)
+	}
+
+	header, frames, err := splitGIF(data)
+	if err != nil {
+		return 0, fmt.Errorf("failed to decode image (detected: GIF): %w", err)
+	}
+	if len(frames) == 0 {
+		return 0, fmt.Errorf("failed to decode image (detected: GIF): no frames")
+	}
+	if len(frames) > maxAnimationFrames {
+		return 0, fmt.Errorf("%w: %d frames, at most %d are supported", ErrImageTooLarge, len(frames), maxAnimationFrames)
+	}
+
+	// The decoder rejects frames outside the logical screen, so the canvas
+	// holds them all.
+	canvas := image.NewRGBA(image.Rect(0, 0, config.Width, config.Height))
+
+	for i, frame := range frames {
+		var buf bytes.Buffer
+		buf.Write(header)
+		buf.Write(frame.control)
+		buf.Write(frame.data)
+		buf.WriteByte(gifTrailer)
+
+		img, err := gif.Decode(&buf)
+		if err != nil {
+			return i, fmt.Errorf("failed to decode image (detected: GIF): frame %d: %w", i+1, err)
+		}
+
+		var disposal byte
+		if len(frame.control) >= 4 {
+			disposal = frame.control[3] >> 2 & 7
+		}
+
+		var previous *image.RGBA
+		if disposal == gif.DisposalPrevious {
+			previous = cloneRGBA(canvas)
+		}
+
+		draw.Draw(canvas, img.Bounds(), img, img.Bounds().Min, draw.Over)
+		if err := fn(i, canvas); err != nil {
+			return i + 1, err
+		}
+
+		switch disposal {
+		case gif.DisposalBackground:
+			draw.Draw(canvas, img.Bounds(), image.Transparent, image.Point{}, draw.Src)
+		case gif.DisposalPrevious:
+			canvas = previous
+		}
+	}
+
+	return len(frames), nil
+}
+
+const (
+	gifExtension  = 0x21
+	gifImage      = 0x2c
+	gifTrailer    = 0x3b
+	gifGraphicExt = 0xf9
+)
+
+// gifFrame is the raw graphic control extension of a frame, if any, and its
+// image descriptor, colour table and image data.
+type gifFrame struct {
+	control []byte
+	data    []byte
+}
+
+// splitGIF returns the header of a GIF, up to the global colour table, and
+// its frames, each of which makes a single-frame GIF once put between the
+// header and a trailer.
+func splitGIF(data []byte) ([]byte, []gifFrame, error) {
+	if len(data) < 13 {
+		return nil, nil, fmt.Errorf("truncated header")
+	}
+	pos := 13
+	if data[10]&0x80 != 0 {
+		pos += 3 << (data[10]&7 + 1)
+	}
+	if pos > len(data) {
+		return nil, nil, fmt.Errorf("truncated colour table")
+	}
+	header := data[:pos]
+
+	var frames []gifFrame
+	var control []byte
+	for pos < len(data) && data[pos] != gifTrailer {
+		start := pos
+		switch data[pos] {
+		case gifExtension:
+			if pos+2 > len(data) {
+				return nil, nil, fmt.Errorf("truncated extension")
+			}
+			label := data[pos+1]
+			end, err := skipGIFSubBlocks(data, pos+2)
+			if err != nil {
+				return nil, nil, err
+			}
+			if label == gifGraphicExt {
+				control = data[start:end]
+			}
+			pos = end
+		case gifImage:
+			if pos+11 > len(data) {
+				return nil, nil, fmt.Errorf("truncated image descriptor")
+			}
+			fields := data[pos+9]
+			pos += 10
+			if fields&0x80 != 0 {
+				pos += 3 << (fields&7 + 1)
+			}
+			// The LZW minimum code size precedes the data sub-blocks.
+			end, err := skipGIFSubBlocks(data, pos+1)
+			if err != nil {
+				return nil, nil, err
+			}
+			frames = append(frames, gifFrame{control: control, data: data[start:end]})
+			control = nil
+			pos = end
+		default:
+			return nil, nil, fmt.Errorf("invalid block type 0x%02x at offset %d", data[pos], pos)
+		}
+	}
+
+	return header, frames, nil
+}
+
+// skipGIFSubBlocks returns the offset after the sub-blocks starting at pos
+// and their terminator.
+func skipGIFSubBlocks(data []byte, pos int) (int, error) {
+	for pos < len(data) {
+		size := int(data[pos])
+		pos++
+		if size == 0 {
+			return pos, nil
+		}
+		pos += size
+	}
+	return 0, fmt.Errorf("truncated data sub-blocks")
+}
+
+type pngChunk struct {
+	kind string
+	data []byte
+}
+
+func readPNGChunks(data []byte) ([]pngChunk, error) {
+	var chunks []pngChunk
+
+	pos := 8
+	for pos+12 <= len(data) {
+		length := int(binary.BigEndian.Uint32(data[pos:]))
+		if length < 0 || pos+12+length > len(data) {
+			return nil, fmt.Errorf("truncated PNG chunk at offset %d", pos)
+		}
+		chunks = append(chunks, pngChunk{
+			kind: string(data[pos+4 : pos+8]),
+			data: data[pos+8 : pos+8+length],
+		})
+		pos += 12 + length
+	}
+
+	return chunks, nil
+}
+
+func isAPNG(data []byte) bool {
+	chunks, err := readPNGChunks(data)
+	if err != nil {
+		return false
+	}
+	for _, chunk := range chunks {
+		switch chunk.kind {
+		case "acTL":
+			return true
+		case "IDAT":
+			return false
+		}
+	}
+	return false
+}
+
+type apngFrame struct {
+	control []byte
+	data    []byte
+}
+
+// apngFrames composes the frames of an animated PNG. Each frame is rebuilt
+// as a standalone PNG from the shared header chunks and its own image data,
+// decoded with the standard library, then drawn onto the canvas according
+// to its blend and dispose operations.
+func apngFrames(data []byte, fn frameFunc) (int, error) {
+	chunks, err := readPNGChunks(data)
+	if err != nil {
+		return 0, fmt.Errorf("failed to decode image (detected: APNG): %w", err)
+	}
+	if len(chunks) == 0 || chunks[0].kind != "IHDR" || len(chunks[0].data) != 13 {
+		return 0, fmt.Errorf("failed to decode image (detected: APNG): missing IHDR chunk")
+	}
+	ihdr := chunks[0].data
+	canvasWidth, canvasHeight := binary.BigEndian.Uint32(ihdr[0:]), binary.BigEndian.Uint32(ihdr[4:])
+	if err := checkImageSize(int(canvasWidth), int(canvasHeight)); err != nil {
+		return 0, fmt.Errorf("failed to decode image (detected: APNG): %w", err)
+	}
+
+	var shared []pngChunk
+	var frames []*apngFrame
+	var current *apngFrame
+	seenData := false
+
+	for _, chunk := range chunks[1:] {
+		switch chunk.kind {
+		case "acTL", "IEND":
+		case "fcTL":
+			if len(chunk.data) < 26 || !frameFits(chunk.data, canvasWidth, canvasHeight) {
+				return 0, fmt.Errorf("failed to decode image (detected: APNG): invalid fcTL chunk")
+			}
+			current = &apngFrame{control: chunk.data}
+			frames = append(frames, current)
+		case "IDAT":
+			seenData = true
+			// The default image only belongs to the animation when a fcTL precedes it.
+			if current != nil {
+				current.data = append(current.data, chunk.data...)
+			}
+		case "fdAT":
+			if current != nil && len(chunk.data) > 4 {
+				current.data = append(current.data, chunk.data[4:]...)
+			}
+		default:
+			if !seenData {
+				shared = append(shared, chunk)
+			}
+		}
+	}
+
+	if len(frames) > maxAnimationFrames {
+		return 0, fmt.Errorf("%w: %d frames, at most %d are supported", ErrImageTooLarge, len(frames), maxAnimationFrames)
+	}
+
+	canvas := image.NewRGBA(image.Rect(0, 0, int(canvasWidth), int(canvasHeight)))
+
+	for i, frame := range frames {
+		width := binary.BigEndian.Uint32(frame.control[4:])
+		height := binary.BigEndian.Uint32(frame.control[8:])
+		x := int(binary.BigEndian.Uint32(frame.control[12:]))
+		y := int(binary.BigEndian.Uint32(frame.control[16:]))
+		dispose, blend := frame.control[24], frame.control[25]
+
+		header := append([]byte(nil), ihdr...)
+		binary.BigEndian.PutUint32(header[0:], width)
+		binary.BigEndian.PutUint32(header[4:], height)
+
+		var buf bytes.Buffer
+		buf.WriteString("\x89PNG\r\n\x1a\n")
+		writePNGChunk(&buf, "IHDR", header)
+		for _, chunk := range shared {
+			writePNGChunk(&buf, chunk.kind, chunk.data)
+		}
+		writePNGChunk(&buf, "IDAT", frame.data)
+		writePNGChunk(&buf, "IEND", nil)
+
+		img, err := png.Decode(&buf)
+		if err != nil {
+			return i, fmt.Errorf("failed to decode image (detected: APNG): frame %d: %w", i+1, err)
+		}
+
+		region := image.Rect(x, y, x+int(width), y+int(height))
+
+		var previous *image.RGBA
+		if dispose == 2 {
+			previous = cloneRGBA(canvas)
+		}
+
+		op := draw.Over
+		if blend == 0 {
+			op = draw.Src
+		}
+		draw.Draw(canvas, region, img, img.Bounds().Min, op)
+
+		if err := fn(i, canvas); err != nil {
+			return i + 1, err
+		}
+
+		switch {
+		case dispose == 1, dispose == 2 && i == 0:
+			draw.Draw(canvas, region, image.Transparent, image.Point{}, draw.Src)
+		case dispose == 2:
+			canvas = previous
+		}
+	}
+
+	return len(frames), nil
+}
+
+// frameFits reports whether the non-empty region of a fcTL chunk lies within
+// the canvas, as the APNG specification requires.
+func frameFits(control []byte, canvasWidth, canvasHeight uint32) bool {
+	width := uint64(binary.BigEndian.Uint32(control[4:]))
+	height := uint64(binary.BigEndian.Uint32(control[8:]))
+	x := uint64(binary.BigEndian.Uint32(control[12:]))
+	y := uint64(binary.BigEndian.Uint32(control[16:]))
+	return width > 0 && height > 0 && x+width <= uint64(canvasWidth) && y+height <= uint64(canvasHeight)
+}
+
+func writePNGChunk(buf *bytes.Buffer, kind string, data []byte) {
+	var length [4]byte
+	binary.BigEndian.PutUint32(length[:], uint32(len(data)))
+	buf.Write(length[:])
+
+	crc := crc32.NewIEEE()
+	crc.Write([]byte(kind))
+	crc.Write(data)
+
+	buf.WriteString(kind)
+	buf.Write(data)
+
+	var sum [4]byte
+	binary.BigEndian.PutUint32(sum[:], crc.Sum32())
+	buf.Write(sum[:])
+}
+
+func cloneRGBA(src *image.RGBA) *image.RGBA {
+	dst := image.NewRGBA(src.Bounds())
+	copy(dst.Pix, src.Pix)
+	return dst
+}
@@ -0,0 +1,144 @@
+package input
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/gif"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/skip2/go-qrcode"
+)
+
+func TestExtractQRCodesFromAnimatedGIF(t *testing.T) {
+	texts := []string{
+		"otpauth-migration://offline?data=first",
+		"otpauth-migration://offline?data=second",
+	}
+
+	palette := color.Palette{color.White, color.Black}
+	anim := &gif.GIF{}
+
+	// Each code is held for several frames, with a blank transition between
+	// them, as in a screen recording.
+	for _, text := range texts {
+		code, err := qrcode.New(text, qrcode.Medium)
+		if err != nil {
+			t.Fatalf("Failed to generate QR code: %v", err)
+		}
+		frame := image.NewPaletted(image.Rect(0, 0, 300, 300), palette)
+		draw.Draw(frame, frame.Bounds(), code.Image(300), image.Point{}, draw.Src)
+		for i := 0; i < 3; i++ {
+			anim.Image = append(anim.Image, frame)
+			anim.Delay = append(anim.Delay, 10)
+		}
+
+		blank := image.NewPaletted(image.Rect(0, 0, 300, 300), palette)
+		anim.Image = append(anim.Image, blank)
+		anim.Delay = append(anim.Delay, 10)
+	}
+
+	var buf bytes.Buffer
+	if err := gif.EncodeAll(&buf, anim); err != nil {
+		t.Fatalf("Failed to encode GIF: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "recording.gif")
+	if err := os.WriteFile(path, buf.Bytes(), 0600); err != nil {
+		t.Fatalf("Failed to write test image: %v", err)
+	}
+
+	results, err := ExtractQRCodes(path)
+	if err != nil {
+		t.Fatalf("Did not expect error but got: %v", err)
+	}
+
+	if len(results) != len(texts) {
+		t.Fatalf("Expected %d distinct codes, got %d", len(texts), len(results))
+	}
+	for i, text := range texts {
+		if results[i].Text != text {
+			t.Errorf("Expected code %d to be '%s', got '%s'", i, text, results[i].Text)
+		}
+	}
+	if results[1].Source != "frame 5" {
+		t.Errorf("Expected second code to come from frame 5, got '%s'", results[1].Source)
+	}
+}
+
+func TestAnimationSizeLimits(t *testing.T) {
+	apng := func(width, height uint32, frame [4]uint32, count int) []byte {
+		var buf bytes.Buffer
+		buf.WriteString("\x89PNG\r\n\x1a\n")
+
+		ihdr := make([]byte, 13)
+		binary.BigEndian.PutUint32(ihdr[0:], width)
+		binary.BigEndian.PutUint32(ihdr[4:], height)
+		ihdr[8], ihdr[9] = 8, 0
+		writePNGChunk(&buf, "IHDR", ihdr)
+		writePNGChunk(&buf, "acTL", make([]byte, 8))
+
+		fctl := make([]byte, 26)
+		for i, v := range frame {
+			binary.BigEndian.PutUint32(fctl[4+4*i:], v)
+		}
+		writePNGChunk(&buf, "fcTL", fctl)
+		writePNGChunk(&buf, "IDAT", nil)
+		for range count - 1 {
+			writePNGChunk(&buf, "fcTL", fctl)
+			writePNGChunk(&buf, "fdAT", make([]byte, 4))
+		}
+		writePNGChunk(&buf, "IEND", nil)
+		return buf.Bytes()
+	}
+
+	var hugeGIF bytes.Buffer
+	anim := &gif.GIF{
+		Image:  []*image.Paletted{image.NewPaletted(image.Rect(0, 0, 1, 1), color.Palette{color.White, color.Black})},
+		Delay:  []int{10},
+		Config: image.Config{Width: 65535, Height: 65535},
+	}
+	if err := gif.EncodeAll(&hugeGIF, anim); err != nil {
+		t.Fatalf("Failed to encode GIF: %v", err)
+	}
+
+	var longGIF bytes.Buffer
+	anim = &gif.GIF{Config: image.Config{Width: 1, Height: 1}}
+	for range maxAnimationFrames + 1 {
+		anim.Image = append(anim.Image, image.NewPaletted(image.Rect(0, 0, 1, 1), color.Palette{color.White, color.Black}))
+		anim.Delay = append(anim.Delay, 10)
+	}
+	if err := gif.EncodeAll(&longGIF, anim); err != nil {
+		t.Fatalf("Failed to encode GIF: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		data     []byte
+		tooLarge bool
+	}{
+		{"Huge APNG canvas", apng(100000, 100000, [4]uint32{1, 1, 0, 0}, 1), true},
+		{"APNG frame outside the canvas", apng(100, 100, [4]uint32{100, 100, 50, 50}, 1), false},
+		{"APNG frame with wrapping offset", apng(100, 100, [4]uint32{10, 10, 1<<32 - 5, 0}, 1), false},
+		{"Empty APNG frame", apng(100, 100, [4]uint32{0, 0, 0, 0}, 1), false},
+		{"Huge GIF screen", hugeGIF.Bytes(), true},
+		{"Too many APNG frames", apng(1, 1, [4]uint32{1, 1, 0, 0}, maxAnimationFrames+1), true},
+		{"Too many GIF frames", longGIF.Bytes(), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ScanQRCodes(tt.data)
+			if err == nil {
+				t.Fatalf("Expected error but got nil")
+			}
+			if errors.Is(err, ErrImageTooLarge) != tt.tooLarge {
+				t.Errorf("Expected ErrImageTooLarge to be %v, got: %v", tt.tooLarge, err)
+			}
+		})
+	}
+}
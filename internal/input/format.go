@@ -0,0 +1,110 @@
+package input
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"strings"
+
+	"golang.org/x/image/bmp"
+	"golang.org/x/image/tiff"
+	"golang.org/x/image/webp"
+)
+
+type imageFormat struct {
+	name   string
+	decode func(r io.Reader) (image.Image, error)
+	// config reads the dimensions from the header, before decode allocates
+	// the pixels.
+	config func(r io.Reader) (image.Config, error)
+}
+
+var (
+	formatPNG  = imageFormat{"PNG", png.Decode, png.DecodeConfig}
+	formatJPEG = imageFormat{"JPEG", jpeg.Decode, jpeg.DecodeConfig}
+	formatGIF  = imageFormat{"GIF", gif.Decode, gif.DecodeConfig}
+	formatWebP = imageFormat{"WebP", webp.Decode, webp.DecodeConfig}
+	formatBMP  = imageFormat{"BMP", bmp.Decode, bmp.DecodeConfig}
+	formatTIFF = imageFormat{"TIFF", tiff.Decode, tiff.DecodeConfig}
+	formatPDF  = imageFormat{name: "PDF"}
+)
+
+// maxImagePixels bounds decoded images and animation canvases, so that a
+// small file declaring huge dimensions cannot exhaust memory. It is far
+// above what any phone screenshot or scan needs.
+const maxImagePixels = 40_000_000
+
+// checkImageSize rejects images with no pixels or more than maxImagePixels.
+func checkImageSize(width, height int) error {
+	if width <= 0 || height <= 0 {
+		return fmt.Errorf("invalid image size %dx%d", width, height)
+	}
+	if width > maxImagePixels/height {
+		return fmt.Errorf("%w: %dx%d pixels, at most %d are supported", ErrImageTooLarge, width, height, maxImagePixels)
+	}
+	return nil
+}
+
+// sniffFormat identifies the file type from its leading bytes. The file
+// extension is never consulted. For recognised but unsupported types the
+// returned format has no decoder.
+func sniffFormat(data []byte) imageFormat {
+	switch {
+	case bytes.HasPrefix(data, []byte("\x89PNG\r\n\x1a\n")):
+		return formatPNG
+	case bytes.HasPrefix(data, []byte("\xff\xd8\xff")):
+		return formatJPEG
+	case bytes.HasPrefix(data, []byte("GIF87a")), bytes.HasPrefix(data, []byte("GIF89a")):
+		return formatGIF
+	case len(data) >= 12 && string(data[:4]) == "RIFF" && string(data[8:12]) == "WEBP":
+		return formatWebP
+	case bytes.HasPrefix(data, []byte("BM")) && len(data) >= 26:
+		return formatBMP
+	case bytes.HasPrefix(data, []byte("II*\x00")), bytes.HasPrefix(data, []byte("MM\x00*")):
+		return formatTIFF
+	case len(data) >= 12 && string(data[4:8]) == "ftyp":
+		switch string(data[8:12]) {
+		case "avif", "avis":
+			return imageFormat{name: "AVIF"}
+		case "heic", "heix", "hevc", "heim", "heis", "mif1", "msf1":
+			return imageFormat{name: "HEIC/HEIF"}
+		}
+		return imageFormat{name: "MP4/MOV video"}
+	case bytes.HasPrefix(data, []byte("\x1a\x45\xdf\xa3")):
+		return imageFormat{name: "WebM/MKV video"}
+	case bytes.HasPrefix(data, []byte("%PDF-")):
+		return formatPDF
+	case bytes.Contains(data[:min(len(data), 512)], []byte("<svg")):
+		return imageFormat{name: "SVG"}
+	}
+	return imageFormat{name: "unknown"}
+}
+
+func decodeImage(data []byte) (image.Image, imageFormat, error) {
+	format := sniffFormat(data)
+	if format.decode == nil {
+		if strings.HasSuffix(format.name, "video") {
+			return nil, format, fmt.Errorf("%w (detected: %s); convert the recording to an animated GIF or APNG first", ErrUnsupportedFormat, format.name)
+		}
+		return nil, format, fmt.Errorf("%w (detected: %s); supported formats are PNG, JPEG, GIF, WebP, BMP, TIFF and PDF", ErrUnsupportedFormat, format.name)
+	}
+
+	config, err := format.config(bytes.NewReader(data))
+	if err != nil {
+		return nil, format, fmt.Errorf("failed to decode image (detected: %s): %w", format.name, err)
+	}
+	if err := checkImageSize(config.Width, config.Height); err != nil {
+		return nil, format, err
+	}
+
+	img, err := format.decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, format, fmt.Errorf("failed to decode image (detected: %s): %w", format.name, err)
+	}
+
+	return img, format, nil
+}
@@ -0,0 +1,86 @@
+package input
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/image/bmp"
+	"golang.org/x/image/tiff"
+)
+
+func TestExtractQRCodeFromImageFormats(t *testing.T) {
+	encoders := map[string]func(io.Writer, image.Image) error{
+		"PNG": png.Encode,
+		"BMP": bmp.Encode,
+		"TIFF": func(w io.Writer, img image.Image) error {
+			return tiff.Encode(w, img, nil)
+		},
+	}
+
+	for format, encode := range encoders {
+		t.Run(format, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := encode(&buf, testQRImage(t, 256)); err != nil {
+				t.Fatalf("Failed to encode %s: %v", format, err)
+			}
+
+			// A misleading extension must not matter: the content is sniffed.
+			path := filepath.Join(t.TempDir(), "screenshot.jpg")
+			if err := os.WriteFile(path, buf.Bytes(), 0600); err != nil {
+				t.Fatalf("Failed to write test image: %v", err)
+			}
+
+			results, err := ExtractQRCodes(path)
+			if err != nil {
+				t.Fatalf("Did not expect error but got: %v", err)
+			}
+			if len(results) != 1 || results[0].Text != testURI {
+				t.Errorf("Expected a single code '%s', got %v", testURI, results)
+			}
+		})
+	}
+}
+
+func TestSniffFormat(t *testing.T) {
+	tests := []struct {
+		data     string
+		expected string
+	}{
+		{"RIFF\x00\x00\x00\x00WEBPVP8 ", "WebP"},
+		{"\x00\x00\x00\x18ftypheic\x00\x00\x00\x00", "HEIC/HEIF"},
+		{"%PDF-1.7\n", "PDF"},
+		{"hello world", "unknown"},
+	}
+
+	for _, tt := range tests {
+		if got := sniffFormat([]byte(tt.data)).name; got != tt.expected {
+			t.Errorf("Expected format '%s', got '%s'", tt.expected, got)
+		}
+	}
+}
+
+func TestDecodeImageUnsupportedFormat(t *testing.T) {
+	_, _, err := decodeImage([]byte("\x00\x00\x00\x18ftypheic\x00\x00\x00\x00"))
+	if !errors.Is(err, ErrUnsupportedFormat) || !strings.Contains(err.Error(), "HEIC/HEIF") {
+		t.Errorf("Expected error naming the detected format, got: %v", err)
+	}
+}
+
+func TestDecodeImageTooLarge(t *testing.T) {
+	var buf bytes.Buffer
+	buf.WriteString("\x89PNG\r\n\x1a\n")
+	writePNGChunk(&buf, "IHDR", []byte{0, 1, 0x86, 0xa0, 0, 1, 0x86, 0xa0, 8, 0, 0, 0, 0})
+	writePNGChunk(&buf, "IDAT", nil)
+	writePNGChunk(&buf, "IEND", nil)
+
+	if _, err := ScanQRCodes(buf.Bytes()); !errors.Is(err, ErrImageTooLarge) {
+		t.Errorf("Expected ErrImageTooLarge for a 100000x100000 PNG, got: %v", err)
+	}
+}
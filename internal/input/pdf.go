@@ -0,0 +1,404 @@
+package input
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"sort"
+
+	"golang.org/x/image/ccitt"
+)
+
+// pdfImageFunc receives each raster image embedded in a PDF, along with the
+// 1-based page number it is drawn on.
+type pdfImageFunc func(page int, img image.Image) error
+
+// pdfImages extracts the raster images used by every page of a PDF, including
+// those nested in form XObjects, and returns the page count. Vector-drawn
+// codes and inline images are not rendered.
+func pdfImages(data []byte, fn pdfImageFunc) (int, error) {
+	doc, err := parsePDF(data)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read PDF: %w", err)
+	}
+	if doc.encrypted() {
+		return 0, fmt.Errorf("failed to read PDF: encrypted PDF files are not supported")
+	}
+
+	pages := doc.pages()
+	if len(pages) == 0 {
+		return 0, fmt.Errorf("failed to read PDF: no pages found")
+	}
+
+	for i, page := range pages {
+		seen := make(map[*pdfStream]bool)
+		for _, stream := range doc.images(page.resources, seen, 0) {
+			img, err := doc.decodeImage(stream, page.resources)
+			if err != nil {
+				// One unsupported image must not hide codes on other pages.
+				continue
+			}
+			if err := fn(i+1, img); err != nil {
+				return len(pages), err
+			}
+		}
+	}
+
+	return len(pages), nil
+}
+
+type pdfPage struct {
+	resources pdfDict
+}
+
+func (d *pdfDoc) catalog() pdfDict {
+	for i := len(d.trailers) - 1; i >= 0; i-- {
+		if root := d.dict(d.trailers[i]["Root"]); root != nil {
+			return root
+		}
+	}
+
+	var nums []int
+	for num := range d.objects {
+		nums = append(nums, num)
+	}
+	sort.Ints(nums)
+	for _, num := range nums {
+		if dict, ok := d.objects[num].(pdfDict); ok && dict["Type"] == pdfName("Catalog") {
+			return dict
+		}
+	}
+	return nil
+}
+
+// pages walks the page tree in document order. Resources are inherited from
+// ancestor nodes when a page does not define its own.
+func (d *pdfDoc) pages() []pdfPage {
+	var pages []pdfPage
+	visited := make(map[int]bool)
+
+	var walk func(node any, inherited pdfDict, depth int)
+	walk = func(node any, inherited pdfDict, depth int) {
+		// Page trees are shallow; a deep one is a chain built to exhaust
+		// the stack.
+		if depth > 64 {
+			return
+		}
+		if ref, ok := node.(pdfRef); ok {
+			if visited[ref.num] {
+				return
+			}
+			visited[ref.num] = true
+		}
+
+		dict := d.dict(node)
+		if dict == nil {
+			return
+		}
+		resources := inherited
+		if r := d.dict(dict["Resources"]); r != nil {
+			resources = r
+		}
+
+		if kids, ok := d.resolve(dict["Kids"]).([]any); ok {
+			for _, kid := range kids {
+				walk(kid, resources, depth+1)
+			}
+			return
+		}
+		if dict["Type"] == pdfName("Page") || dict["Type"] == nil {
+			pages = append(pages, pdfPage{resources: resources})
+		}
+	}
+
+	if catalog := d.catalog(); catalog != nil {
+		walk(catalog["Pages"], nil, 0)
+	}
+	return pages
+}
+
+// images returns the image XObjects reachable from a resource dictionary,
+// descending into form XObjects.
+func (d *pdfDoc) images(resources pdfDict, seen map[*pdfStream]bool, depth int) []*pdfStream {
+	xobjects := d.dict(resources["XObject"])
+	if xobjects == nil || depth > 8 {
+		return nil
+	}
+
+	names := make([]string, 0, len(xobjects))
+	for name := range xobjects {
+		names = append(names, string(name))
+	}
+	sort.Strings(names)
+
+	var list []*pdfStream
+	for _, name := range names {
+		stream, ok := d.resolve(xobjects[pdfName(name)]).(*pdfStream)
+		if !ok || seen[stream] {
+			continue
+		}
+		seen[stream] = true
+
+		switch stream.dict["Subtype"] {
+		case pdfName("Image"):
+			list = append(list, stream)
+		case pdfName("Form"):
+			nested := d.dict(stream.dict["Resources"])
+			if nested == nil {
+				nested = resources
+			}
+			list = append(list, d.images(nested, seen, depth+1)...)
+		}
+	}
+	return list
+}
+
+type pdfColorSpace struct {
+	components int
+	kind       string
+	base       *pdfColorSpace
+	hival      int
+	lookup     []byte
+}
+
+func (d *pdfDoc) colorSpace(v any, resources pdfDict, depth int) (*pdfColorSpace, error) {
+	if depth > 4 {
+		return nil, fmt.Errorf("color space nesting too deep")
+	}
+
+	v = d.resolve(v)
+	var family pdfName
+	var args []any
+	switch value := v.(type) {
+	case pdfName:
+		family = value
+	case []any:
+		if len(value) == 0 {
+			return nil, fmt.Errorf("empty color space")
+		}
+		family, _ = d.resolve(value[0]).(pdfName)
+		args = value[1:]
+	}
+
+	switch family {
+	case "DeviceGray", "CalGray", "G":
+		return &pdfColorSpace{components: 1, kind: "gray"}, nil
+	case "DeviceRGB", "CalRGB", "RGB":
+		return &pdfColorSpace{components: 3, kind: "rgb"}, nil
+	case "Lab":
+		// The lightness channel alone is a good enough luminance.
+		return &pdfColorSpace{components: 3, kind: "lab"}, nil
+	case "DeviceCMYK", "CMYK":
+		return &pdfColorSpace{components: 4, kind: "cmyk"}, nil
+	case "ICCBased":
+		if len(args) > 0 {
+			if stream, ok := d.resolve(args[0]).(*pdfStream); ok {
+				switch d.integer(stream.dict["N"]) {
+				case 1:
+					return &pdfColorSpace{components: 1, kind: "gray"}, nil
+				case 4:
+					return &pdfColorSpace{components: 4, kind: "cmyk"}, nil
+				}
+			}
+		}
+		return &pdfColorSpace{components: 3, kind: "rgb"}, nil
+	case "Separation":
+		return &pdfColorSpace{components: 1, kind: "ink"}, nil
+	case "Indexed", "I":
+		if len(args) < 3 {
+			return nil, fmt.Errorf("invalid Indexed color space")
+		}
+		base, err := d.colorSpace(args[0], resources, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		var lookup []byte
+		switch table := d.resolve(args[2]).(type) {
+		case string:
+			lookup = []byte(table)
+		case *pdfStream:
+			if lookup, err = d.decodeStream(table); err != nil {
+				return nil, err
+			}
+		}
+		// Clamp the highest index to the table, so that every sample maps to
+		// a colour in it.
+		entries := len(lookup) / base.components
+		if entries == 0 {
+			return nil, fmt.Errorf("invalid Indexed color space: empty lookup table")
+		}
+		hival := min(max(d.integer(args[1]), 0), entries-1)
+		return &pdfColorSpace{components: 1, kind: "indexed", base: base, hival: hival, lookup: lookup}, nil
+	}
+
+	// Anything else may be the name of a color space defined in the resources.
+	if named := d.dict(resources["ColorSpace"]); named != nil && family != "" && args == nil {
+		if cs, ok := named[family]; ok {
+			return d.colorSpace(cs, resources, depth+1)
+		}
+	}
+	return nil, fmt.Errorf("unsupported color space %v", v)
+}
+
+// luminance converts component values (each scaled to 0-255) to a gray level.
+func (cs *pdfColorSpace) luminance(c []int) uint8 {
+	switch cs.kind {
+	case "rgb":
+		return uint8((c[0] + 2*c[1] + c[2]) / 4)
+	case "lab":
+		return uint8(c[0])
+	case "cmyk":
+		r := (255 - c[0]) * (255 - c[3]) / 255
+		g := (255 - c[1]) * (255 - c[3]) / 255
+		b := (255 - c[2]) * (255 - c[3]) / 255
+		return uint8((r + 2*g + b) / 4)
+	case "ink":
+		return uint8(255 - c[0])
+	}
+	return uint8(c[0])
+}
+
+func (d *pdfDoc) decodeImage(stream *pdfStream, resources pdfDict) (image.Image, error) {
+	width := d.integer(stream.dict["Width"])
+	height := d.integer(stream.dict["Height"])
+	if err := checkImageSize(width, height); err != nil {
+		return nil, err
+	}
+
+	// At most four components of 16 bits per pixel, and a predictor byte
+	// per row.
+	data, codec, params, err := d.applyFilters(stream, width*height*8+height)
+	if err != nil {
+		return nil, err
+	}
+
+	switch codec {
+	case "":
+	case "DCTDecode", "DCT":
+		// The JPEG header, not the image dictionary, sets the decoded size.
+		config, err := jpeg.DecodeConfig(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		if err := checkImageSize(config.Width, config.Height); err != nil {
+			return nil, err
+		}
+		return jpeg.Decode(bytes.NewReader(data))
+	case "CCITTFaxDecode", "CCF":
+		return d.decodeCCITT(data, params, width, height)
+	default:
+		return nil, fmt.Errorf("unsupported image filter %s", codec)
+	}
+
+	mask, _ := stream.dict["ImageMask"].(bool)
+	bpc := d.integer(stream.dict["BitsPerComponent"])
+	if mask || bpc == 0 {
+		bpc = 1
+	}
+	if bpc != 1 && bpc != 2 && bpc != 4 && bpc != 8 && bpc != 16 {
+		return nil, fmt.Errorf("unsupported bits per component %d", bpc)
+	}
+
+	// Stencil masks paint where the sample is 0, which reads as black on gray.
+	var cs *pdfColorSpace
+	if mask {
+		cs = &pdfColorSpace{components: 1, kind: "gray"}
+	} else if cs, err = d.colorSpace(stream.dict["ColorSpace"], resources, 0); err != nil {
+		return nil, err
+	}
+
+	invert := false
+	if decode := d.array(stream.dict["Decode"]); len(decode) >= 2 {
+		invert = d.number(decode[0]) > d.number(decode[1])
+	}
+
+	return rasterToGray(data, width, height, bpc, cs, invert), nil
+}
+
+func (d *pdfDoc) decodeCCITT(data []byte, params pdfDict, width, height int) (image.Image, error) {
+	k := d.integer(params["K"])
+	sf := ccitt.Group4
+	if k >= 0 {
+		sf = ccitt.Group3
+	}
+	if columns := d.integer(params["Columns"]); columns > 0 {
+		width = columns
+		if err := checkImageSize(width, height); err != nil {
+			return nil, err
+		}
+	}
+	blackIs1, _ := params["BlackIs1"].(bool)
+	align, _ := params["EncodedByteAlign"].(bool)
+
+	img := image.NewGray(image.Rect(0, 0, width, height))
+	err := ccitt.DecodeIntoGray(img, bytes.NewReader(data), ccitt.MSB, sf, &ccitt.Options{
+		Align:  align,
+		Invert: blackIs1,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("CCITTFaxDecode: %w", err)
+	}
+	return img, nil
+}
+
+// rasterToGray unpacks raw image samples, with rows padded to whole bytes, and
+// converts them to gray levels.
+func rasterToGray(data []byte, width, height, bpc int, cs *pdfColorSpace, invert bool) *image.Gray {
+	img := image.NewGray(image.Rect(0, 0, width, height))
+
+	maxValue := 1<<bpc - 1
+	rowBits := width * cs.components * bpc
+	rowBytes := (rowBits + 7) / 8
+
+	sample := func(row []byte, index int) int {
+		switch bpc {
+		case 8:
+			return int(row[index])
+		case 16:
+			return int(row[index*2])
+		}
+		bit := index * bpc
+		shift := 8 - bpc - bit%8
+		return int(row[bit/8]>>shift) & maxValue
+	}
+	scale := func(v int) int {
+		if bpc == 16 {
+			return v
+		}
+		return v * 255 / maxValue
+	}
+
+	values := make([]int, 4)
+	for y := 0; y < height; y++ {
+		if (y+1)*rowBytes > len(data) {
+			break
+		}
+		row := data[y*rowBytes : (y+1)*rowBytes]
+
+		for x := 0; x < width; x++ {
+			var lum uint8
+			if cs.kind == "indexed" {
+				index := min(sample(row, x), cs.hival)
+				n := cs.base.components
+				if (index+1)*n <= len(cs.lookup) {
+					for i := 0; i < n; i++ {
+						values[i] = int(cs.lookup[index*n+i])
+					}
+					lum = cs.base.luminance(values)
+				}
+			} else {
+				for i := 0; i < cs.components; i++ {
+					values[i] = scale(sample(row, x*cs.components+i))
+				}
+				lum = cs.luminance(values)
+			}
+			if invert {
+				lum = 255 - lum
+			}
+			img.Pix[y*img.Stride+x] = lum
+		}
+	}
+
+	return img
+}
@@ -0,0 +1,170 @@
+package input
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"image/jpeg"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/skip2/go-qrcode"
+)
+
+// buildTestPDF writes a two-page PDF: page 1 draws a Flate-compressed gray
+// image whose length is an indirect object, page 2 a JPEG image.
+func buildTestPDF(t *testing.T, first, second string) []byte {
+	t.Helper()
+
+	code, err := qrcode.New(first, qrcode.Medium)
+	if err != nil {
+		t.Fatalf("Failed to generate QR code: %v", err)
+	}
+	gray := toGray(code.Image(200))
+	var flated bytes.Buffer
+	zw := zlib.NewWriter(&flated)
+	zw.Write(gray.Pix)
+	zw.Close()
+
+	code, err = qrcode.New(second, qrcode.Medium)
+	if err != nil {
+		t.Fatalf("Failed to generate QR code: %v", err)
+	}
+	var jpg bytes.Buffer
+	if err := jpeg.Encode(&jpg, code.Image(200), &jpeg.Options{Quality: 90}); err != nil {
+		t.Fatalf("Failed to encode JPEG: %v", err)
+	}
+
+	var pdf bytes.Buffer
+	pdf.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	pdf.WriteString("1 0 obj\n<< /Type /Catalog /Pages 2 0 R >>\nendobj\n")
+	pdf.WriteString("2 0 obj\n<< /Type /Pages /Kids [3 0 R 4 0 R] /Count 2 >>\nendobj\n")
+	pdf.WriteString("3 0 obj\n<< /Type /Page /Parent 2 0 R /MediaBox [0 0 200 200] /Resources << /XObject << /Im1 5 0 R >> >> >>\nendobj\n")
+	pdf.WriteString("4 0 obj\n<< /Type /Page /Parent 2 0 R /MediaBox [0 0 200 200] /Resources << /XObject << /Im2 7 0 R >> >> >>\nendobj\n")
+	pdf.WriteString("5 0 obj\n<< /Type /XObject /Subtype /Image /Width 200 /Height 200 /ColorSpace /DeviceGray /BitsPerComponent 8 /Filter /FlateDecode /Length 6 0 R >>\nstream\n")
+	pdf.Write(flated.Bytes())
+	pdf.WriteString("\nendstream\nendobj\n")
+	fmt.Fprintf(&pdf, "6 0 obj\n%d\nendobj\n", flated.Len())
+	fmt.Fprintf(&pdf, "7 0 obj\n<< /Type /XObject /Subtype /Image /Width 200 /Height 200 /ColorSpace /DeviceRGB /BitsPerComponent 8 /Filter /DCTDecode /Length %d >>\nstream\n", jpg.Len())
+	pdf.Write(jpg.Bytes())
+	pdf.WriteString("\nendstream\nendobj\n")
+	pdf.WriteString("trailer\n<< /Root 1 0 R /Size 8 >>\n%%EOF\n")
+
+	return pdf.Bytes()
+}
+
+func TestExtractQRCodesFromPDF(t *testing.T) {
+	first := "otpauth-migration://offline?data=page1"
+	second := "otpauth-migration://offline?data=page2"
+
+	path := filepath.Join(t.TempDir(), "printout.pdf")
+	if err := os.WriteFile(path, buildTestPDF(t, first, second), 0600); err != nil {
+		t.Fatalf("Failed to write test PDF: %v", err)
+	}
+
+	results, err := ExtractQRCodes(path)
+	if err != nil {
+		t.Fatalf("Did not expect error but got: %v", err)
+	}
+
+	if len(results) != 2 {
+		t.Fatalf("Expected 2 codes, got %d", len(results))
+	}
+	for i, expected := range []struct{ text, source string }{{first, "page 1"}, {second, "page 2"}} {
+		if results[i].Text != expected.text {
+			t.Errorf("Expected code %d to be '%s', got '%s'", i, expected.text, results[i].Text)
+		}
+		if results[i].Source != expected.source {
+			t.Errorf("Expected code %d to come from '%s', got '%s'", i, expected.source, results[i].Source)
+		}
+	}
+}
+
+func TestPDFFilters(t *testing.T) {
+	hex, err := asciiHexDecode([]byte("48 65 6c6C6f>"))
+	if err != nil || string(hex) != "Hello" {
+		t.Errorf("ASCIIHexDecode: expected 'Hello', got '%s' (%v)", hex, err)
+	}
+
+	a85, err := ascii85Decode([]byte("<~87cURD]i,\"Ebo80~>"))
+	if err != nil || string(a85) != "Hello World!" {
+		t.Errorf("ASCII85Decode: expected 'Hello World!', got '%s' (%v)", a85, err)
+	}
+
+	rle := runLengthDecode([]byte{2, 'a', 'b', 'c', 254, 'z', 128})
+	if string(rle) != "abczzz" {
+		t.Errorf("RunLengthDecode: expected 'abczzz', got '%s'", rle)
+	}
+}
+
+// buildImagePDF writes a one-page PDF drawing a single image object with the
+// given dictionary entries and stream data.
+func buildImagePDF(image string, data []byte) []byte {
+	var pdf bytes.Buffer
+	pdf.WriteString("%PDF-1.4\n")
+	pdf.WriteString("1 0 obj\n<< /Type /Catalog /Pages 2 0 R >>\nendobj\n")
+	pdf.WriteString("2 0 obj\n<< /Type /Pages /Kids [3 0 R] /Count 1 >>\nendobj\n")
+	pdf.WriteString("3 0 obj\n<< /Type /Page /Parent 2 0 R /MediaBox [0 0 200 200] /Resources << /XObject << /Im1 4 0 R >> >> >>\nendobj\n")
+	fmt.Fprintf(&pdf, "4 0 obj\n<< /Type /XObject /Subtype /Image %s /Length %d >>\nstream\n", image, len(data))
+	pdf.Write(data)
+	pdf.WriteString("\nendstream\nendobj\n")
+	pdf.WriteString("trailer\n<< /Root 1 0 R /Size 5 >>\n%%EOF\n")
+	return pdf.Bytes()
+}
+
+func TestMalformedPDFs(t *testing.T) {
+	objStm := []byte("3 -50 << /Type /Page >>")
+	var objStmPDF bytes.Buffer
+	objStmPDF.WriteString("%PDF-1.5\n")
+	fmt.Fprintf(&objStmPDF, "1 0 obj\n<< /Type /ObjStm /N 1 /First 6 /Length %d >>\nstream\n%s\nendstream\nendobj\n", len(objStm), objStm)
+	objStmPDF.WriteString("trailer\n<< /Root 2 0 R /Size 4 >>\n%%EOF\n")
+
+	var streams bytes.Buffer
+	streams.WriteString("%PDF-1.4\n")
+	for i := 1; i <= 100000; i++ {
+		fmt.Fprintf(&streams, "%d 0 obj << /Length 1 >> stream\n", i)
+	}
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"Negative object stream offset", objStmPDF.Bytes()},
+		{"Deeply nested arrays", append([]byte("%PDF-1.4\n1 0 obj\n"), bytes.Repeat([]byte("["), 10<<20)...)},
+		{"Deeply nested dictionaries", append([]byte("%PDF-1.4\n1 0 obj\n"), bytes.Repeat([]byte("<< /A "), 1<<20)...)},
+		{"Streams without endstream", streams.Bytes()},
+		{"Negative Indexed hival", buildImagePDF("/Width 4 /Height 4 /BitsPerComponent 8 /ColorSpace [/Indexed /DeviceGray -5 <00FF>]", bytes.Repeat([]byte{1}, 16))},
+		{"Empty Indexed lookup", buildImagePDF("/Width 4 /Height 4 /BitsPerComponent 8 /ColorSpace [/Indexed /DeviceGray 1 <>]", bytes.Repeat([]byte{1}, 16))},
+		{"Huge CCITT columns", buildImagePDF("/Width 10 /Height 100000 /ImageMask true /Filter /CCITTFaxDecode /DecodeParms << /K -1 /Columns 100000000 >>", []byte{0})},
+		{"Huge image", buildImagePDF("/Width 100000 /Height 100000 /BitsPerComponent 8 /ColorSpace /DeviceGray", []byte{0})},
+		{"Bad predictor", buildImagePDF("/Width 4 /Height 4 /BitsPerComponent 8 /ColorSpace /DeviceGray /Filter /FlateDecode /DecodeParms << /Predictor 12 /BitsPerComponent -8 >>", deflate(make([]byte, 16)))},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ScanQRCodes(tt.data); err == nil {
+				t.Errorf("Expected error but got nil")
+			}
+		})
+	}
+}
+
+func TestInflateLimit(t *testing.T) {
+	bomb := deflate(make([]byte, 1<<20))
+
+	if _, err := inflate(bomb, 1000); err == nil {
+		t.Errorf("Expected an error for data inflating beyond the limit")
+	}
+	if out, err := inflate(bomb, 1<<20); err != nil || len(out) != 1<<20 {
+		t.Errorf("Expected %d bytes, got %d (%v)", 1<<20, len(out), err)
+	}
+}
+
+func deflate(data []byte) []byte {
+	var b bytes.Buffer
+	zw := zlib.NewWriter(&b)
+	zw.Write(data)
+	zw.Close()
+	return b.Bytes()
+}
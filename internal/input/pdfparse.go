@@ -0,0 +1,731 @@
+package input
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+)
+
+// A minimal reader for the PDF object syntax: just enough to walk the page
+// tree and pull image streams out of a document. Objects are located by
+// scanning for "N G obj" headers rather than through the cross-reference
+// table, which also copes with damaged files and incremental updates.
+
+type pdfName string
+
+type pdfRef struct {
+	num, gen int
+}
+
+type pdfDict map[pdfName]any
+
+type pdfStream struct {
+	dict pdfDict
+	data []byte
+}
+
+type pdfDoc struct {
+	objects  map[int]any
+	trailers []pdfDict
+}
+
+var (
+	pdfObjHeader = regexp.MustCompile(`(\d+)[\x00\t\n\f\r ]+(\d+)[\x00\t\n\f\r ]+obj\b`)
+	pdfTrailer   = regexp.MustCompile(`trailer[\x00\t\n\f\r ]*<<`)
+	pdfEndstream = regexp.MustCompile(`endstream`)
+)
+
+// maxPDFNesting bounds nested arrays and dictionaries, which are parsed
+// recursively. Real documents stay within a handful of levels.
+const maxPDFNesting = 128
+
+func parsePDF(data []byte) (*pdfDoc, error) {
+	doc := &pdfDoc{objects: make(map[int]any)}
+
+	endstreams := []int{}
+	for _, loc := range pdfEndstream.FindAllIndex(data, -1) {
+		endstreams = append(endstreams, loc[0])
+	}
+
+	pos := 0
+	for {
+		loc := pdfObjHeader.FindSubmatchIndex(data[pos:])
+		if loc == nil {
+			break
+		}
+		num, _ := strconv.Atoi(string(data[pos+loc[2] : pos+loc[3]]))
+
+		lex := &pdfLexer{data: data, pos: pos + loc[1], endstreams: endstreams}
+		obj, err := lex.parseIndirect()
+		// A damaged object is skipped up to where it failed to parse, so
+		// that no byte is parsed more than once.
+		pos = max(lex.pos, pos+loc[1])
+		if err == nil {
+			doc.objects[num] = obj
+		}
+	}
+
+	end := 0
+	for _, loc := range pdfTrailer.FindAllIndex(data, -1) {
+		if loc[0] < end {
+			continue
+		}
+		lex := &pdfLexer{data: data, pos: loc[1] - 2}
+		obj, err := lex.parseObject()
+		end = lex.pos
+		if dict, ok := obj.(pdfDict); ok && err == nil {
+			doc.trailers = append(doc.trailers, dict)
+		}
+	}
+
+	if len(doc.objects) == 0 {
+		return nil, fmt.Errorf("no PDF objects found")
+	}
+
+	doc.expandObjectStreams()
+	return doc, nil
+}
+
+// expandObjectStreams adds the objects packed in compressed object streams
+// (PDF 1.5+), where page dictionaries of modern files usually live.
+func (d *pdfDoc) expandObjectStreams() {
+	for _, obj := range d.objects {
+		stream, ok := obj.(*pdfStream)
+		if !ok {
+			continue
+		}
+		switch stream.dict["Type"] {
+		case pdfName("XRef"):
+			d.trailers = append(d.trailers, stream.dict)
+			continue
+		case pdfName("ObjStm"):
+		default:
+			continue
+		}
+
+		data, err := d.decodeStream(stream)
+		if err != nil {
+			continue
+		}
+		count := d.integer(stream.dict["N"])
+		first := d.integer(stream.dict["First"])
+		if first <= 0 || first > len(data) {
+			continue
+		}
+
+		header := &pdfLexer{data: data[:first]}
+		end := first
+		for i := 0; i < count; i++ {
+			num, err1 := header.parseObject()
+			offset, err2 := header.parseObject()
+			if err1 != nil || err2 != nil {
+				break
+			}
+			n, _ := num.(int)
+			off, _ := offset.(int)
+			// Objects are stored in order; an offset into an object already
+			// parsed would parse the same bytes again.
+			if _, exists := d.objects[n]; exists || n <= 0 || off < 0 || first+off < end || first+off >= len(data) {
+				continue
+			}
+			lex := &pdfLexer{data: data, pos: first + off}
+			value, err := lex.parseObject()
+			end = lex.pos
+			if err == nil {
+				d.objects[n] = value
+			}
+		}
+	}
+}
+
+func (d *pdfDoc) resolve(v any) any {
+	for i := 0; i < 32; i++ {
+		ref, ok := v.(pdfRef)
+		if !ok {
+			return v
+		}
+		v = d.objects[ref.num]
+	}
+	return nil
+}
+
+func (d *pdfDoc) dict(v any) pdfDict {
+	switch value := d.resolve(v).(type) {
+	case pdfDict:
+		return value
+	case *pdfStream:
+		return value.dict
+	}
+	return nil
+}
+
+func (d *pdfDoc) array(v any) []any {
+	switch value := d.resolve(v).(type) {
+	case []any:
+		return value
+	case nil:
+		return nil
+	default:
+		return []any{value}
+	}
+}
+
+func (d *pdfDoc) integer(v any) int {
+	switch value := d.resolve(v).(type) {
+	case int:
+		return value
+	case float64:
+		return int(value)
+	}
+	return 0
+}
+
+func (d *pdfDoc) number(v any) float64 {
+	switch value := d.resolve(v).(type) {
+	case int:
+		return float64(value)
+	case float64:
+		return value
+	}
+	return 0
+}
+
+func (d *pdfDoc) encrypted() bool {
+	for _, trailer := range d.trailers {
+		if _, ok := trailer["Encrypt"]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// decodeStream applies the stream's filters, stopping before image codecs
+// (DCT, CCITT, JBIG2, JPX) which are handled by the image decoder.
+func (d *pdfDoc) decodeStream(stream *pdfStream) ([]byte, error) {
+	data, _, _, err := d.applyFilters(stream, maxStreamSize)
+	return data, err
+}
+
+// maxStreamSize bounds inflated streams that are not images, such as object
+// streams and colour tables. Images are bounded by their dimensions.
+const maxStreamSize = 16 << 20
+
+// applyFilters decodes the stream, failing when Flate data inflates to more
+// than limit bytes.
+func (d *pdfDoc) applyFilters(stream *pdfStream, limit int) ([]byte, pdfName, pdfDict, error) {
+	filters := d.array(stream.dict["Filter"])
+	params := d.array(stream.dict["DecodeParms"])
+
+	data := stream.data
+	for i, f := range filters {
+		name, _ := d.resolve(f).(pdfName)
+		var param pdfDict
+		if i < len(params) {
+			param = d.dict(params[i])
+		}
+
+		var err error
+		switch name {
+		case "FlateDecode", "Fl":
+			data, err = inflate(data, limit)
+			if err == nil {
+				data, err = unpredict(data, param, d)
+			}
+		case "ASCIIHexDecode", "AHx":
+			data, err = asciiHexDecode(data)
+		case "ASCII85Decode", "A85":
+			data, err = ascii85Decode(data)
+		case "RunLengthDecode", "RL":
+			data = runLengthDecode(data)
+		default:
+			return data, name, param, nil
+		}
+		if err != nil {
+			return nil, name, param, fmt.Errorf("%s: %w", name, err)
+		}
+	}
+
+	return data, "", nil, nil
+}
+
+func inflate(data []byte, limit int) ([]byte, error) {
+	r, err := zlib.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	out, err := io.ReadAll(io.LimitReader(r, int64(limit)+1))
+	if len(out) > limit {
+		return nil, fmt.Errorf("stream inflates to more than %d bytes", limit)
+	}
+	// Many writers produce streams with a missing or bad checksum; keep
+	// whatever was inflated.
+	if err != nil && len(out) == 0 {
+		return nil, err
+	}
+	return out, nil
+}
+
+// unpredict reverses the PNG row predictors that may follow Flate encoding.
+func unpredict(data []byte, params pdfDict, d *pdfDoc) ([]byte, error) {
+	predictor := d.integer(params["Predictor"])
+	if predictor < 10 {
+		return data, nil
+	}
+
+	colors := max(d.integer(params["Colors"]), 1)
+	bpc := d.integer(params["BitsPerComponent"])
+	if bpc == 0 {
+		bpc = 8
+	}
+	columns := max(d.integer(params["Columns"]), 1)
+	if colors > 32 || bpc < 1 || bpc > 16 || columns > len(data)*8 {
+		return nil, fmt.Errorf("invalid predictor parameters")
+	}
+
+	bpp := max(colors*bpc/8, 1)
+	rowLen := (colors*bpc*columns + 7) / 8
+
+	out := make([]byte, 0, len(data))
+	prev := make([]byte, rowLen)
+	for pos := 0; pos+1+rowLen <= len(data); pos += 1 + rowLen {
+		filter := data[pos]
+		row := append([]byte(nil), data[pos+1:pos+1+rowLen]...)
+		for i := range row {
+			var left, upLeft byte
+			if i >= bpp {
+				left = row[i-bpp]
+				upLeft = prev[i-bpp]
+			}
+			up := prev[i]
+			switch filter {
+			case 1:
+				row[i] += left
+			case 2:
+				row[i] += up
+			case 3:
+				row[i] += byte((int(left) + int(up)) / 2)
+			case 4:
+				row[i] += paeth(left, up, upLeft)
+			}
+		}
+		out = append(out, row...)
+		prev = row
+	}
+
+	return out, nil
+}
+
+func paeth(a, b, c byte) byte {
+	p := int(a) + int(b) - int(c)
+	pa, pb, pc := abs(p-int(a)), abs(p-int(b)), abs(p-int(c))
+	switch {
+	case pa <= pb && pa <= pc:
+		return a
+	case pb <= pc:
+		return b
+	}
+	return c
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+func asciiHexDecode(data []byte) ([]byte, error) {
+	var out []byte
+	var high byte
+	half := false
+	for _, c := range data {
+		var v byte
+		switch {
+		case c >= '0' && c <= '9':
+			v = c - '0'
+		case c >= 'a' && c <= 'f':
+			v = c - 'a' + 10
+		case c >= 'A' && c <= 'F':
+			v = c - 'A' + 10
+		case c == '>':
+			if half {
+				out = append(out, high<<4)
+			}
+			return out, nil
+		case isPDFSpace(c):
+			continue
+		default:
+			return nil, fmt.Errorf("invalid hex digit %q", c)
+		}
+		if half {
+			out = append(out, high<<4|v)
+		} else {
+			high = v
+		}
+		half = !half
+	}
+	return out, nil
+}
+
+func ascii85Decode(data []byte) ([]byte, error) {
+	var out []byte
+	var group [5]byte
+	n := 0
+	flush := func(count int) {
+		for i := count; i < 5; i++ {
+			group[i] = 'u' - '!'
+		}
+		var v uint32
+		for _, g := range group {
+			v = v*85 + uint32(g)
+		}
+		word := []byte{byte(v >> 24), byte(v >> 16), byte(v >> 8), byte(v)}
+		out = append(out, word[:count-1]...)
+	}
+
+	data = bytes.TrimPrefix(bytes.TrimSpace(data), []byte("<~"))
+	for _, c := range data {
+		switch {
+		case c == '~':
+			if n > 1 {
+				flush(n)
+			}
+			return out, nil
+		case c == 'z' && n == 0:
+			out = append(out, 0, 0, 0, 0)
+		case c >= '!' && c <= 'u':
+			group[n] = c - '!'
+			n++
+			if n == 5 {
+				flush(5)
+				n = 0
+			}
+		case isPDFSpace(c):
+		default:
+			return nil, fmt.Errorf("invalid ASCII85 character %q", c)
+		}
+	}
+	if n > 1 {
+		flush(n)
+	}
+	return out, nil
+}
+
+func runLengthDecode(data []byte) []byte {
+	var out []byte
+	for i := 0; i < len(data); {
+		n := int(data[i])
+		i++
+		switch {
+		case n == 128:
+			return out
+		case n < 128:
+			end := min(i+n+1, len(data))
+			out = append(out, data[i:end]...)
+			i = end
+		case i < len(data):
+			out = append(out, bytes.Repeat(data[i:i+1], 257-n)...)
+			i++
+		}
+	}
+	return out
+}
+
+type pdfLexer struct {
+	data []byte
+	pos  int
+	// depth counts the arrays and dictionaries being parsed.
+	depth int
+	// endstreams holds the offsets of every "endstream" keyword in data,
+	// when not nil, so that streams with a wrong length are not searched for
+	// again and again.
+	endstreams []int
+}
+
+func isPDFSpace(c byte) bool {
+	switch c {
+	case 0, '\t', '\n', '\f', '\r', ' ':
+		return true
+	}
+	return false
+}
+
+func isPDFDelimiter(c byte) bool {
+	switch c {
+	case '(', ')', '<', '>', '[', ']', '{', '}', '/', '%':
+		return true
+	}
+	return isPDFSpace(c)
+}
+
+func (l *pdfLexer) skipSpace() {
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		if c == '%' {
+			for l.pos < len(l.data) && l.data[l.pos] != '\n' && l.data[l.pos] != '\r' {
+				l.pos++
+			}
+			continue
+		}
+		if !isPDFSpace(c) {
+			return
+		}
+		l.pos++
+	}
+}
+
+func (l *pdfLexer) keyword() string {
+	l.skipSpace()
+	start := l.pos
+	for l.pos < len(l.data) && !isPDFDelimiter(l.data[l.pos]) {
+		l.pos++
+	}
+	return string(l.data[start:l.pos])
+}
+
+// parseIndirect parses the body of an indirect object, after "N G obj",
+// including a stream if the object has one.
+func (l *pdfLexer) parseIndirect() (any, error) {
+	obj, err := l.parseObject()
+	if err != nil {
+		return nil, err
+	}
+
+	dict, ok := obj.(pdfDict)
+	if !ok {
+		return obj, nil
+	}
+
+	save := l.pos
+	if l.keyword() != "stream" {
+		l.pos = save
+		return dict, nil
+	}
+	if l.pos < len(l.data) && l.data[l.pos] == '\r' {
+		l.pos++
+	}
+	if l.pos < len(l.data) && l.data[l.pos] == '\n' {
+		l.pos++
+	}
+	start := l.pos
+
+	// Trust /Length when it is direct and lands on "endstream"; otherwise
+	// search for the keyword, since the length is often an indirect object.
+	if length, ok := dict["Length"].(int); ok && length >= 0 && start+length <= len(l.data) {
+		rest := bytes.TrimLeft(l.data[start+length:min(start+length+32, len(l.data))], "\x00\t\n\f\r ")
+		if bytes.HasPrefix(rest, []byte("endstream")) {
+			l.pos = start + length
+			return &pdfStream{dict: dict, data: l.data[start : start+length]}, nil
+		}
+	}
+
+	end := -1
+	if l.endstreams != nil {
+		if i := sort.SearchInts(l.endstreams, start); i < len(l.endstreams) {
+			end = l.endstreams[i] - start
+		}
+	} else {
+		end = bytes.Index(l.data[start:], []byte("endstream"))
+	}
+	if end < 0 {
+		return nil, fmt.Errorf("unterminated stream")
+	}
+	data := bytes.TrimRight(l.data[start:start+end], "\r\n")
+	l.pos = start + end + len("endstream")
+	return &pdfStream{dict: dict, data: data}, nil
+}
+
+func (l *pdfLexer) parseObject() (any, error) {
+	l.skipSpace()
+	if l.pos >= len(l.data) {
+		return nil, io.ErrUnexpectedEOF
+	}
+
+	c := l.data[l.pos]
+	switch {
+	case c == '/':
+		l.pos++
+		return l.name(), nil
+	case c == '<' && l.pos+1 < len(l.data) && l.data[l.pos+1] == '<':
+		l.pos += 2
+		if err := l.nest(); err != nil {
+			return nil, err
+		}
+		defer func() { l.depth-- }()
+		return l.dictionary()
+	case c == '<':
+		l.pos++
+		end := bytes.IndexByte(l.data[l.pos:], '>')
+		if end < 0 {
+			return nil, io.ErrUnexpectedEOF
+		}
+		s, err := asciiHexDecode(append(l.data[l.pos:l.pos+end:l.pos+end], '>'))
+		l.pos += end + 1
+		return string(s), err
+	case c == '(':
+		l.pos++
+		return l.literalString()
+	case c == '[':
+		l.pos++
+		if err := l.nest(); err != nil {
+			return nil, err
+		}
+		defer func() { l.depth-- }()
+		var arr []any
+		for {
+			l.skipSpace()
+			if l.pos >= len(l.data) {
+				return nil, io.ErrUnexpectedEOF
+			}
+			if l.data[l.pos] == ']' {
+				l.pos++
+				return arr, nil
+			}
+			v, err := l.parseObject()
+			if err != nil {
+				return nil, err
+			}
+			arr = append(arr, v)
+		}
+	case c == '+' || c == '-' || c == '.' || (c >= '0' && c <= '9'):
+		return l.numberOrRef()
+	}
+
+	switch word := l.keyword(); word {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	case "null":
+		return nil, nil
+	case "":
+		l.pos++
+		return nil, fmt.Errorf("unexpected character %q", c)
+	default:
+		return nil, fmt.Errorf("unexpected keyword %q", word)
+	}
+}
+
+// nest enters an array or dictionary.
+func (l *pdfLexer) nest() error {
+	if l.depth >= maxPDFNesting {
+		return fmt.Errorf("objects nested more than %d levels deep at offset %d", maxPDFNesting, l.pos)
+	}
+	l.depth++
+	return nil
+}
+
+func (l *pdfLexer) name() pdfName {
+	var buf []byte
+	for l.pos < len(l.data) && !isPDFDelimiter(l.data[l.pos]) {
+		c := l.data[l.pos]
+		if c == '#' && l.pos+2 < len(l.data) {
+			if v, err := strconv.ParseUint(string(l.data[l.pos+1:l.pos+3]), 16, 8); err == nil {
+				buf = append(buf, byte(v))
+				l.pos += 3
+				continue
+			}
+		}
+		buf = append(buf, c)
+		l.pos++
+	}
+	return pdfName(buf)
+}
+
+func (l *pdfLexer) dictionary() (pdfDict, error) {
+	dict := make(pdfDict)
+	for {
+		l.skipSpace()
+		if l.pos+1 < len(l.data) && l.data[l.pos] == '>' && l.data[l.pos+1] == '>' {
+			l.pos += 2
+			return dict, nil
+		}
+		if l.pos >= len(l.data) || l.data[l.pos] != '/' {
+			return nil, fmt.Errorf("expected name in dictionary at offset %d", l.pos)
+		}
+		l.pos++
+		key := l.name()
+		value, err := l.parseObject()
+		if err != nil {
+			return nil, err
+		}
+		dict[key] = value
+	}
+}
+
+func (l *pdfLexer) literalString() (string, error) {
+	var buf []byte
+	depth := 1
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		l.pos++
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return string(buf), nil
+			}
+		case '\\':
+			if l.pos >= len(l.data) {
+				break
+			}
+			e := l.data[l.pos]
+			l.pos++
+			switch e {
+			case 'n':
+				c = '\n'
+			case 'r':
+				c = '\r'
+			case 't':
+				c = '\t'
+			case 'b':
+				c = '\b'
+			case 'f':
+				c = '\f'
+			case '\r', '\n':
+				if e == '\r' && l.pos < len(l.data) && l.data[l.pos] == '\n' {
+					l.pos++
+				}
+				continue
+			default:
+				if e >= '0' && e <= '7' {
+					v := int(e - '0')
+					for i := 0; i < 2 && l.pos < len(l.data) && l.data[l.pos] >= '0' && l.data[l.pos] <= '7'; i++ {
+						v = v*8 + int(l.data[l.pos]-'0')
+						l.pos++
+					}
+					c = byte(v)
+				} else {
+					c = e
+				}
+			}
+		}
+		buf = append(buf, c)
+	}
+	return "", io.ErrUnexpectedEOF
+}
+
+func (l *pdfLexer) numberOrRef() (any, error) {
+	token := l.keyword()
+	if i, err := strconv.Atoi(token); err == nil {
+		// An integer may be the start of an indirect reference "N G R".
+		save := l.pos
+		gen := l.keyword()
+		if g, err := strconv.Atoi(gen); err == nil && g >= 0 && l.keyword() == "R" {
+			return pdfRef{num: i, gen: g}, nil
+		}
+		l.pos = save
+		return i, nil
+	}
+	f, err := strconv.ParseFloat(token, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid number %q", token)
+	}
+	return f, nil
+}
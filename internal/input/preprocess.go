@@ -0,0 +1,229 @@
+package input
+
+import (
+	"fmt"
+	"image"
+	"image/draw"
+	"strings"
+
+	"github.com/makiuchi-d/gozxing"
+	"github.com/makiuchi-d/gozxing/qrcode"
+)
+
+// Images smaller than this (longest side, in pixels) are also tried upscaled,
+// larger ones are also tried downscaled.
+const (
+	upscaleBelow   = 1000
+	downscaleAbove = 1200
+)
+
+type variant struct {
+	name  string
+	image func() *image.Gray
+}
+
+type binarizer struct {
+	name string
+	new  func(gozxing.LuminanceSource) gozxing.Binarizer
+}
+
+var binarizers = []binarizer{
+	{"hybrid", gozxing.NewHybridBinarizer},
+	{"global histogram", gozxing.NewGlobalHistgramBinarizer},
+}
+
+// ScanImage looks for a QR code in img, retrying with a series of
+// preprocessing strategies until one of them decodes. orientation is the
+// EXIF orientation tag (1-8) of the source file, or 0 if unknown.
+func ScanImage(img image.Image, orientation int) (*ScanResult, error) {
+
+	gray := applyOrientation(toGray(img), orientation)
+
+	result, ok := scanVariants(append(rescaled(gray), crops(gray)...))
+	if !ok {
+		return nil, fmt.Errorf("%w after trying all preprocessing strategies", ErrNoQRCode)
+	}
+	if orientation > 1 {
+		result.Strategy += fmt.Sprintf(", EXIF orientation %d", orientation)
+	}
+
+	return result, nil
+}
+
+// scanFrame is the lighter pipeline used for animation frames, where most
+// frames are expected to show a full-size code or none at all.
+func scanFrame(gray *image.Gray) (*ScanResult, bool) {
+	return scanVariants(rescaled(gray))
+}
+
+func scanVariants(list []variant) (*ScanResult, bool) {
+	reader := qrcode.NewQRCodeReader()
+	hints := map[gozxing.DecodeHintType]interface{}{
+		gozxing.DecodeHintType_TRY_HARDER: true,
+	}
+
+	for _, v := range list {
+		source := gozxing.NewLuminanceSourceFromImage(v.image())
+
+		for _, inverted := range []bool{false, true} {
+			src := source
+			if inverted {
+				src = source.Invert()
+			}
+
+			for _, b := range binarizers {
+				bmp, err := gozxing.NewBinaryBitmap(b.new(src))
+				if err != nil {
+					continue
+				}
+
+				result, err := reader.Decode(bmp, hints)
+				reader.Reset()
+				if err != nil {
+					continue
+				}
+
+				return &ScanResult{
+					Text:     result.String(),
+					Strategy: describeStrategy(v.name, b.name, inverted),
+				}, true
+			}
+		}
+	}
+
+	return nil, false
+}
+
+func describeStrategy(variant, binarizer string, inverted bool) string {
+	parts := []string{binarizer + " binarizer"}
+	if inverted {
+		parts = append(parts, "inverted colours")
+	}
+	if variant != "" {
+		parts = append(parts, variant)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// rescaled returns the image as-is followed by rescaled copies: shrunk for
+// large photos, enlarged for small images where modules are only a pixel or
+// two wide.
+func rescaled(gray *image.Gray) []variant {
+	size := gray.Bounds().Size()
+	longest := max(size.X, size.Y)
+
+	list := []variant{{"", func() *image.Gray { return gray }}}
+
+	// Shrinking also helps mid-size images: the detector sometimes misreads
+	// version 7+ codes drawn with wide modules.
+	for _, factor := range []float64{0.5, 0.25} {
+		if factor == 0.25 && longest <= downscaleAbove {
+			break
+		}
+		if float64(longest)*factor >= 200 {
+			list = append(list, scaled(gray, factor))
+		}
+	}
+
+	if longest < upscaleBelow {
+		for _, factor := range []float64{2, 3} {
+			list = append(list, scaled(gray, factor))
+		}
+	}
+
+	return list
+}
+
+// crops returns overlapping tiles of the image, for codes that only cover a
+// small part of a large screenshot or photo.
+func crops(gray *image.Gray) []variant {
+	var list []variant
+	for _, grid := range []int{2, 3} {
+		for row := 0; row < grid; row++ {
+			for col := 0; col < grid; col++ {
+				list = append(list, crop(gray, grid, row, col))
+			}
+		}
+	}
+	return list
+}
+
+func scaled(gray *image.Gray, factor float64) variant {
+	return variant{
+		name:  fmt.Sprintf("scaled x%g", factor),
+		image: func() *image.Gray { return scaleGray(gray, factor) },
+	}
+}
+
+// crop returns the tile at (row, col) of a grid x grid split of gray. Tiles
+// overlap by half a tile so a code on a boundary is fully inside one of them,
+// and small tiles are upscaled so their modules stay several pixels wide.
+func crop(gray *image.Gray, grid, row, col int) variant {
+	return variant{
+		name: fmt.Sprintf("crop %d/%d of %dx%d grid", row*grid+col+1, grid*grid, grid, grid),
+		image: func() *image.Gray {
+			b := gray.Bounds()
+			tileW, tileH := b.Dx()/grid, b.Dy()/grid
+			rect := image.Rect(
+				b.Min.X+col*tileW-tileW/4, b.Min.Y+row*tileH-tileH/4,
+				b.Min.X+(col+1)*tileW+tileW/4, b.Min.Y+(row+1)*tileH+tileH/4,
+			).Intersect(b)
+
+			tile := image.NewGray(image.Rect(0, 0, rect.Dx(), rect.Dy()))
+			draw.Draw(tile, tile.Bounds(), gray, rect.Min, draw.Src)
+
+			if longest := max(rect.Dx(), rect.Dy()); longest < upscaleBelow && longest > 0 {
+				return scaleGray(tile, float64(upscaleBelow)/float64(longest))
+			}
+			return tile
+		},
+	}
+}
+
+func toGray(img image.Image) *image.Gray {
+	if gray, ok := img.(*image.Gray); ok && gray.Bounds().Min == (image.Point{}) {
+		return gray
+	}
+
+	b := img.Bounds()
+	gray := image.NewGray(image.Rect(0, 0, b.Dx(), b.Dy()))
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			r, g, bl, a := img.At(x, y).RGBA()
+			// Composite transparent pixels onto white, as a screenshot viewer
+			// would. The colour is already premultiplied by alpha.
+			lum := (r+2*g+bl)/4 + 0xffff - a
+			gray.Pix[(y-b.Min.Y)*gray.Stride+(x-b.Min.X)] = uint8(lum >> 8)
+		}
+	}
+	return gray
+}
+
+// scaleGray resizes src by factor, averaging source pixels when shrinking and
+// using nearest-neighbour sampling when enlarging so module edges stay sharp.
+func scaleGray(src *image.Gray, factor float64) *image.Gray {
+	sw, sh := src.Bounds().Dx(), src.Bounds().Dy()
+	dw, dh := max(int(float64(sw)*factor), 1), max(int(float64(sh)*factor), 1)
+	dst := image.NewGray(image.Rect(0, 0, dw, dh))
+
+	for y := 0; y < dh; y++ {
+		y0 := y * sh / dh
+		y1 := max((y+1)*sh/dh, y0+1)
+		for x := 0; x < dw; x++ {
+			x0 := x * sw / dw
+			x1 := max((x+1)*sw/dw, x0+1)
+
+			sum, n := 0, 0
+			for sy := y0; sy < y1; sy++ {
+				row := src.Pix[sy*src.Stride:]
+				for sx := x0; sx < x1; sx++ {
+					sum += int(row[sx])
+					n++
+				}
+			}
+			dst.Pix[y*dst.Stride+x] = uint8(sum / n)
+		}
+	}
+
+	return dst
+}
@@ -0,0 +1,142 @@
+package input
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"image/draw"
+	"testing"
+
+	"github.com/skip2/go-qrcode"
+)
+
+const testURI = "otpauth-migration://offline?data=CjoKFD1jwRTgK6xTGKA0gdTWaGMebxmTEg1UT1RQZ2VuZXJhdG9yGg1UT1RQZ2VuZXJhdG9yIAMoAjACEAEYASAAKJ2G1g0%3D"
+
+func testQRImage(t *testing.T, size int) *image.Gray {
+	t.Helper()
+
+	qr, err := qrcode.New(testURI, qrcode.Medium)
+	if err != nil {
+		t.Fatalf("Failed to generate QR code: %v", err)
+	}
+	return toGray(qr.Image(size))
+}
+
+func invert(img *image.Gray) *image.Gray {
+	out := image.NewGray(img.Bounds())
+	for i, v := range img.Pix {
+		out.Pix[i] = 255 - v
+	}
+	return out
+}
+
+func TestScanImage(t *testing.T) {
+	tests := []struct {
+		name        string
+		image       func(t *testing.T) image.Image
+		orientation int
+	}{
+		{
+			name:  "Plain code",
+			image: func(t *testing.T) image.Image { return testQRImage(t, 256) },
+		},
+		{
+			name:  "Dark mode inverted code",
+			image: func(t *testing.T) image.Image { return invert(testQRImage(t, 256)) },
+		},
+		{
+			name: "Small code in a large screenshot",
+			image: func(t *testing.T) image.Image {
+				canvas := image.NewGray(image.Rect(0, 0, 2400, 1600))
+				draw.Draw(canvas, canvas.Bounds(), image.NewUniform(color.Gray{Y: 230}), image.Point{}, draw.Src)
+				code := testQRImage(t, 180)
+				draw.Draw(canvas, code.Bounds().Add(image.Pt(1900, 1200)), code, image.Point{}, draw.Src)
+				return canvas
+			},
+		},
+		{
+			name: "Rotated photo with EXIF orientation",
+			image: func(t *testing.T) image.Image {
+				return applyOrientation(testQRImage(t, 256), 6)
+			},
+			orientation: 8,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := ScanImage(tt.image(t), tt.orientation)
+			if err != nil {
+				t.Fatalf("Did not expect error but got: %v", err)
+			}
+			if result.Text != testURI {
+				t.Errorf("Expected '%s', got '%s'", testURI, result.Text)
+			}
+			if result.Strategy == "" {
+				t.Errorf("Expected the successful strategy to be reported")
+			}
+		})
+	}
+}
+
+func TestToGray(t *testing.T) {
+	tests := []struct {
+		name     string
+		color    color.Color
+		expected uint8
+	}{
+		{"Opaque black", color.NRGBA{0, 0, 0, 255}, 0},
+		{"Opaque white", color.NRGBA{255, 255, 255, 255}, 255},
+		{"Transparent", color.NRGBA{0, 0, 0, 0}, 255},
+		{"Half-transparent black", color.NRGBA{0, 0, 0, 128}, 127},
+		{"Half-transparent white", color.NRGBA{255, 255, 255, 128}, 255},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			img := image.NewNRGBA(image.Rect(0, 0, 1, 1))
+			img.Set(0, 0, tt.color)
+			if got := toGray(img).Pix[0]; got != tt.expected {
+				t.Errorf("Expected %d, got %d", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestScanImageMidSizeVersion10(t *testing.T) {
+	// gozxing misreads this version 10 code at 512px unless it is shrunk.
+	uri := "otpauth-migration://offline?data=CiIKCkhlbGwPId6tvugSDlRlc3QgYWNjb3VudCAxIAEoATACCiIKCgBlbGxvId6tvu8SDlRlc3QgYWNjb3VudCAyIAEoATACCiMKCgBEjWxkLzvjHR8SDUNvdW50ZXIga2V5IDEgASgBMAE4ARABGAEgACj8nJf4Bg%3D%3D"
+
+	qr, err := qrcode.New(uri, qrcode.Medium)
+	if err != nil {
+		t.Fatalf("Failed to generate QR code: %v", err)
+	}
+
+	result, err := ScanImage(qr.Image(512), 0)
+	if err != nil {
+		t.Fatalf("Did not expect error but got: %v", err)
+	}
+	if result.Text != uri {
+		t.Errorf("Expected '%s', got '%s'", uri, result.Text)
+	}
+}
+
+func TestScanImageWithoutCode(t *testing.T) {
+	blank := image.NewGray(image.Rect(0, 0, 300, 300))
+	if _, err := ScanImage(blank, 0); !errors.Is(err, ErrNoQRCode) {
+		t.Errorf("Expected ErrNoQRCode for an image without a QR code, got: %v", err)
+	}
+}
+
+func TestApplyOrientationRoundTrip(t *testing.T) {
+	img := testQRImage(t, 64)
+	for orientation, inverse := range map[int]int{2: 2, 3: 3, 4: 4, 5: 5, 6: 8, 7: 7, 8: 6} {
+		got := applyOrientation(applyOrientation(img, orientation), inverse)
+		for i := range img.Pix {
+			if got.Pix[i] != img.Pix[i] {
+				t.Errorf("Orientation %d followed by %d did not restore the image", orientation, inverse)
+				break
+			}
+		}
+	}
+}
@@ -1,40 +1,144 @@
 package input
 
 import (
+	"errors"
 	"fmt"
+	"hash/fnv"
 	"image"
-	_ "image/gif"
-	_ "image/jpeg"
-	_ "image/png"
 	"os"
+)
 
-	"github.com/makiuchi-d/gozxing"
-	"github.com/makiuchi-d/gozxing/qrcode"
+var (
+	ErrNoQRCode          = errors.New("failed to decode QR code: no QR code found")
+	ErrUnsupportedFormat = errors.New("unsupported image format")
+	ErrImageTooLarge     = errors.New("image too large")
 )
 
-func ExtractQRCodeFromImage(imagePath string) (string, error) {
+type ScanResult struct {
+	Text     string
+	Strategy string
+	Source   string
+}
+
+// ExtractQRCodes returns the distinct QR codes found in an image file. Still
+// images yield a single code; for animated GIF and PNG files every frame is
+// scanned so that all export batches shown in a recording are collected, and
+// for PDF files every image embedded in every page.
+func ExtractQRCodes(imagePath string) ([]ScanResult, error) {
 
-	file, err := os.Open(imagePath)
+	data, err := os.ReadFile(imagePath)
 	if err != nil {
-		return "", fmt.Errorf("failed to open image file: %w", err)
+		return nil, fmt.Errorf("failed to open image file: %w", err)
 	}
-	defer file.Close()
+	return ScanQRCodes(data)
+}
 
-	img, _, err := image.Decode(file)
+// ScanQRCodes finds the QR codes in the contents of an image or PDF file.
+func ScanQRCodes(data []byte) ([]ScanResult, error) {
+	format := sniffFormat(data)
+	switch {
+	case format.name == formatGIF.name:
+		return scanAnimation(data, gifFrames)
+	case format.name == formatPNG.name && isAPNG(data):
+		return scanAnimation(data, apngFrames)
+	case format.name == formatPDF.name:
+		return scanPDF(data)
+	}
+
+	img, format, err := decodeImage(data)
 	if err != nil {
-		return "", fmt.Errorf("failed to decode image: %w", err)
+		return nil, err
 	}
 
-	bmp, err := gozxing.NewBinaryBitmapFromImage(img)
+	result, err := ScanImage(img, orientationOf(format, data))
 	if err != nil {
-		return "", fmt.Errorf("failed to convert image to binary bitmap: %w", err)
+		return nil, err
 	}
+	result.Strategy = format.name + ", " + result.Strategy
 
-	reader := qrcode.NewQRCodeReader()
-	result, err := reader.Decode(bmp, nil)
+	return []ScanResult{*result}, nil
+}
+
+func scanAnimation(data []byte, frames func([]byte, frameFunc) (int, error)) ([]ScanResult, error) {
+	var results []ScanResult
+	var first *image.Gray
+	seen := make(map[string]bool)
+	var lastHash uint64
+
+	count, err := frames(data, func(index int, frame image.Image) error {
+		gray := toGray(frame)
+
+		// Recordings hold the same picture for many frames; scan it once.
+		hash := fnv.New64a()
+		hash.Write(gray.Pix)
+		if index > 0 && hash.Sum64() == lastHash {
+			return nil
+		}
+		lastHash = hash.Sum64()
+
+		result, ok := scanFrame(gray)
+		if !ok {
+			if index == 0 {
+				first = gray
+			}
+			return nil
+		}
+		if !seen[result.Text] {
+			seen[result.Text] = true
+			result.Source = fmt.Sprintf("frame %d", index+1)
+			results = append(results, *result)
+		}
+		return nil
+	})
 	if err != nil {
-		return "", fmt.Errorf("failed to decode QR code: %w", err)
+		return nil, err
+	}
+
+	// A single-frame file gets the full pipeline, like any still image.
+	if count == 1 && first != nil {
+		result, err := ScanImage(first, 0)
+		if err != nil {
+			return nil, err
+		}
+		return []ScanResult{*result}, nil
+	}
+
+	if len(results) == 0 {
+		return nil, fmt.Errorf("%w in any of the %d frames", ErrNoQRCode, count)
+	}
+
+	return results, nil
+}
+
+func scanPDF(data []byte) ([]ScanResult, error) {
+	var results []ScanResult
+	seen := make(map[string]bool)
+	images := 0
+
+	pages, err := pdfImages(data, func(page int, img image.Image) error {
+		images++
+
+		result, err := ScanImage(img, 0)
+		if err != nil {
+			return nil
+		}
+		if !seen[result.Text] {
+			seen[result.Text] = true
+			result.Source = fmt.Sprintf("page %d", page)
+			results = append(results, *result)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if images == 0 {
+		return nil, fmt.Errorf("%w: the PDF has no embedded raster images (QR codes drawn as vector graphics are not supported)", ErrNoQRCode)
+	}
+	if len(results) == 0 {
+		return nil, fmt.Errorf("%w in the %d image(s) on %d page(s) of the PDF", ErrNoQRCode, images, pages)
 	}
 
-	return result.String(), nil
+	return results, nil
 }
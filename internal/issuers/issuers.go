@@ -0,0 +1,211 @@
+package issuers
+
+import (
+	_ "embed"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"unicode"
+
+	"github.com/Zaphkiel-Ivanovna/GoogleAuthExtractor/internal/decoder"
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed catalogue.yaml
+var builtinCatalogue []byte
+
+var ErrInvalidCatalogue = errors.New("invalid issuer catalogue")
+
+type Service struct {
+	Name    string   `yaml:"name"`
+	Domains []string `yaml:"domains"`
+	Aliases []string `yaml:"aliases"`
+}
+
+// Rules is the format of the built-in catalogue and of user rule files.
+// User services are looked up before the built-in ones, so they can also
+// rename a well-known service.
+type Rules struct {
+	Services []Service `yaml:"services"`
+	Webmail  []string  `yaml:"webmail"`
+}
+
+type Catalogue struct {
+	byKey    map[string]string
+	byDomain map[string]string
+	webmail  map[string]bool
+}
+
+// Builtin returns a catalogue of well-known services.
+func Builtin() *Catalogue {
+	c := &Catalogue{
+		byKey:    make(map[string]string),
+		byDomain: make(map[string]string),
+		webmail:  make(map[string]bool),
+	}
+
+	rules, err := ParseRules(builtinCatalogue)
+	if err != nil {
+		panic(err)
+	}
+	c.add(rules, false)
+	return c
+}
+
+func LoadRules(path string) (*Rules, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read issuer rules '%s': %w", path, err)
+	}
+
+	rules, err := ParseRules(data)
+	if err != nil {
+		return nil, fmt.Errorf("'%s': %w", path, err)
+	}
+	return rules, nil
+}
+
+func ParseRules(data []byte) (*Rules, error) {
+	var rules Rules
+	if err := yaml.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidCatalogue, err)
+	}
+
+	for i, service := range rules.Services {
+		if strings.TrimSpace(service.Name) == "" {
+			return nil, fmt.Errorf("%w: service %d has no name", ErrInvalidCatalogue, i+1)
+		}
+	}
+	return &rules, nil
+}
+
+// Extend adds user rules, which take precedence over the existing entries.
+func (c *Catalogue) Extend(rules *Rules) {
+	c.add(rules, true)
+}
+
+func (c *Catalogue) add(rules *Rules, override bool) {
+	set := func(m map[string]string, key, name string) {
+		if _, exists := m[key]; key != "" && (override || !exists) {
+			m[key] = name
+		}
+	}
+
+	for _, service := range rules.Services {
+		set(c.byKey, key(service.Name), service.Name)
+		for _, alias := range service.Aliases {
+			set(c.byKey, key(alias), service.Name)
+		}
+		for _, domain := range service.Domains {
+			domain = strings.ToLower(strings.TrimSpace(domain))
+			set(c.byDomain, domain, service.Name)
+			set(c.byKey, key(domain), service.Name)
+		}
+	}
+
+	for _, domain := range rules.Webmail {
+		c.webmail[strings.ToLower(strings.TrimSpace(domain))] = true
+	}
+}
+
+// key reduces a name to lower-case letters and digits, so that "Git Hub",
+// "github" and "GitHub.com" compare equal to their catalogue entries.
+func key(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToLower(r)
+		}
+		return -1
+	}, s)
+}
+
+// Lookup returns the canonical name of a service from its name, an alias,
+// or a domain, including subdomains ("accounts.github.com").
+func (c *Catalogue) Lookup(issuer string) (string, bool) {
+	issuer = strings.TrimSpace(issuer)
+	if issuer == "" {
+		return "", false
+	}
+
+	if name, ok := c.lookupDomain(issuer); ok {
+		return name, true
+	}
+
+	name, ok := c.byKey[key(issuer)]
+	return name, ok
+}
+
+func (c *Catalogue) lookupDomain(s string) (string, bool) {
+	domain := strings.ToLower(s)
+	domain = strings.TrimPrefix(strings.TrimPrefix(domain, "https://"), "http://")
+	domain, _, _ = strings.Cut(domain, "/")
+	if !strings.Contains(domain, ".") || strings.ContainsAny(domain, " @") {
+		return "", false
+	}
+
+	for {
+		if name, ok := c.byDomain[domain]; ok {
+			return name, true
+		}
+		_, parent, ok := strings.Cut(domain, ".")
+		if !ok || !strings.Contains(parent, ".") {
+			return "", false
+		}
+		domain = parent
+	}
+}
+
+// Infer guesses the issuer of an account without one from its name: an
+// "Issuer:" label prefix, or the domain of an email address, as long as the
+// domain belongs to a known service and is not a webmail provider. It also
+// returns the name without the prefix when the prefix gave the issuer.
+func (c *Catalogue) Infer(name string) (issuer, rest string, ok bool) {
+	if prefix, label, found := strings.Cut(name, ":"); found {
+		prefix, label = strings.TrimSpace(prefix), strings.TrimSpace(label)
+		if label == "" {
+			label = name
+		}
+		if canonical, ok := c.Lookup(prefix); ok {
+			return canonical, label, true
+		}
+		if prefix != "" && !strings.Contains(prefix, "@") {
+			return prefix, label, true
+		}
+	}
+
+	if at := strings.LastIndex(name, "@"); at >= 0 {
+		domain := strings.ToLower(strings.TrimSpace(name[at+1:]))
+		if !c.webmail[domain] {
+			issuer, ok := c.lookupDomain(domain)
+			return issuer, name, ok
+		}
+	}
+
+	return "", name, false
+}
+
+// Normalize replaces the issuer of each account by its canonical name and,
+// when infer is set, fills in missing issuers from the account names. It
+// returns the number of accounts whose issuer changed.
+func (c *Catalogue) Normalize(accounts []decoder.Account, infer bool) ([]decoder.Account, int) {
+	result := make([]decoder.Account, len(accounts))
+	changed := 0
+
+	for i, account := range accounts {
+		issuer := strings.TrimSpace(account.Issuer)
+		if canonical, ok := c.Lookup(issuer); ok {
+			issuer = canonical
+		} else if issuer == "" && infer {
+			issuer, account.Name, _ = c.Infer(account.Name)
+		}
+
+		if issuer != account.Issuer {
+			account.Issuer = issuer
+			changed++
+		}
+		result[i] = account
+	}
+
+	return result, changed
+}
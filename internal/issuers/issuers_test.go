@@ -0,0 +1,116 @@
+package issuers
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/Zaphkiel-Ivanovna/GoogleAuthExtractor/internal/decoder"
+)
+
+func TestLookup(t *testing.T) {
+	catalogue := Builtin()
+
+	tests := []struct {
+		issuer   string
+		expected string
+		found    bool
+	}{
+		{"Github", "GitHub", true},
+		{"GitHub.com", "GitHub", true},
+		{" github ", "GitHub", true},
+		{"https://github.com/login", "GitHub", true},
+		{"accounts.google.com", "Google", true},
+		{"aws", "Amazon Web Services", true},
+		{"Twitter", "X", true},
+		{"Example Corp", "", false},
+		{"", "", false},
+	}
+
+	for _, tt := range tests {
+		name, found := catalogue.Lookup(tt.issuer)
+		if name != tt.expected || found != tt.found {
+			t.Errorf("Lookup(%q): expected %q (%v), got %q (%v)", tt.issuer, tt.expected, tt.found, name, found)
+		}
+	}
+}
+
+func TestInfer(t *testing.T) {
+	catalogue := Builtin()
+
+	tests := []struct {
+		name     string
+		expected string
+		rest     string
+	}{
+		{"github.com:alice", "GitHub", "alice"},
+		{"Example: alice", "Example", "alice"},
+		{"Example:", "Example", "Example:"},
+		{"alice@gitlab.com", "GitLab", "alice@gitlab.com"},
+		{"alice@gmail.com", "", "alice@gmail.com"},
+		{"alice@corp.com", "", "alice@corp.com"},
+		{"alice", "", "alice"},
+	}
+
+	for _, tt := range tests {
+		got, rest, _ := catalogue.Infer(tt.name)
+		if got != tt.expected || rest != tt.rest {
+			t.Errorf("Infer(%q): expected %q and %q, got %q and %q", tt.name, tt.expected, tt.rest, got, rest)
+		}
+	}
+}
+
+func TestNormalizeWithRules(t *testing.T) {
+	rules, err := ParseRules([]byte(`
+services:
+  - name: Corp SSO
+    domains: [corp.com]
+    aliases: [corp]
+  - name: Git Hub Enterprise
+    aliases: [github]
+`))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	catalogue := Builtin()
+	catalogue.Extend(rules)
+
+	accounts := []decoder.Account{
+		{Name: "alice@corp.com"},
+		{Name: "GitHub:frank"},
+		{Name: "bob", Issuer: "CORP"},
+		{Name: "carol", Issuer: "github"},
+		{Name: "dave", Issuer: "gitlab.com"},
+		{Name: "erin", Issuer: "Unknown"},
+	}
+
+	expected := []string{"Corp SSO", "Git Hub Enterprise", "Corp SSO", "Git Hub Enterprise", "GitLab", "Unknown"}
+
+	result, changed := catalogue.Normalize(accounts, true)
+	var got []string
+	for _, account := range result {
+		got = append(got, account.Issuer)
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %v, got %v", expected, got)
+	}
+	if changed != 5 {
+		t.Errorf("Expected 5 changed accounts, got %d", changed)
+	}
+	if result[1].Name != "frank" {
+		t.Errorf("Expected the issuer prefix to be removed from the name, got %q", result[1].Name)
+	}
+
+	if result, _ := catalogue.Normalize(accounts[:1], false); result[0].Issuer != "" {
+		t.Errorf("Expected no inference when disabled, got %q", result[0].Issuer)
+	}
+}
+
+func TestParseRulesErrors(t *testing.T) {
+	for _, data := range []string{"services: [{domains: [x.com]}]", "services: yes"} {
+		if _, err := ParseRules([]byte(data)); !errors.Is(err, ErrInvalidCatalogue) {
+			t.Errorf("Expected ErrInvalidCatalogue for %q, got %v", data, err)
+		}
+	}
+}
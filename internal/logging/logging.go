@@ -0,0 +1,149 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/fatih/color"
+	"github.com/mattn/go-isatty"
+)
+
+// LevelSuccess sits between info and warning so that --quiet hides it along
+// with other progress messages.
+const LevelSuccess = slog.Level(2)
+
+var (
+	level  = new(slog.LevelVar)
+	logger = slog.New(NewRedactingHandler(NewConsoleHandler(os.Stderr, level)))
+)
+
+// SetLevel changes the minimum level of messages written by the package
+// logger. --quiet maps to slog.LevelWarn and --verbose to slog.LevelDebug.
+func SetLevel(l slog.Level) {
+	level.Set(l)
+}
+
+// SetHandler replaces the handler behind the package logger. Records are
+// still redacted before they reach it.
+func SetHandler(h slog.Handler) {
+	logger = slog.New(NewRedactingHandler(h))
+}
+
+func Logger() *slog.Logger {
+	return logger
+}
+
+func Debugf(format string, args ...any) {
+	logf(slog.LevelDebug, format, args...)
+}
+
+func Infof(format string, args ...any) {
+	logf(slog.LevelInfo, format, args...)
+}
+
+func Successf(format string, args ...any) {
+	logf(LevelSuccess, format, args...)
+}
+
+func Warnf(format string, args ...any) {
+	logf(slog.LevelWarn, format, args...)
+}
+
+func Errorf(format string, args ...any) {
+	logf(slog.LevelError, format, args...)
+}
+
+func logf(l slog.Level, format string, args ...any) {
+	ctx := context.Background()
+	if !logger.Enabled(ctx, l) {
+		return
+	}
+	logger.Log(ctx, l, fmt.Sprintf(format, args...))
+}
+
+// ConsoleHandler writes one human-readable, optionally coloured line per
+// record. Colour is only used when the destination is a terminal and NO_COLOR
+// is not set.
+type ConsoleHandler struct {
+	mu     *sync.Mutex
+	w      io.Writer
+	level  slog.Leveler
+	colors bool
+	attrs  []slog.Attr
+}
+
+func NewConsoleHandler(w io.Writer, level slog.Leveler) *ConsoleHandler {
+	colors := false
+	if f, ok := w.(*os.File); ok && os.Getenv("NO_COLOR") == "" {
+		colors = isatty.IsTerminal(f.Fd()) || isatty.IsCygwinTerminal(f.Fd())
+	}
+	return &ConsoleHandler{mu: &sync.Mutex{}, w: w, level: level, colors: colors}
+}
+
+func (h *ConsoleHandler) Enabled(_ context.Context, l slog.Level) bool {
+	return l >= h.level.Level()
+}
+
+func (h *ConsoleHandler) Handle(_ context.Context, r slog.Record) error {
+	var b strings.Builder
+
+	var c *color.Color
+	switch {
+	case r.Level >= slog.LevelError:
+		c = color.New(color.FgRed)
+		b.WriteString("Error: ")
+	case r.Level >= slog.LevelWarn:
+		c = color.New(color.FgYellow)
+		b.WriteString("Warning: ")
+	case r.Level >= LevelSuccess:
+		c = color.New(color.FgGreen)
+	case r.Level < slog.LevelInfo:
+		c = color.New(color.Faint)
+	}
+
+	b.WriteString(r.Message)
+	for _, a := range h.attrs {
+		writeAttr(&b, a)
+	}
+	r.Attrs(func(a slog.Attr) bool {
+		writeAttr(&b, a)
+		return true
+	})
+
+	line := b.String()
+	if c != nil {
+		if h.colors {
+			c.EnableColor()
+		} else {
+			c.DisableColor()
+		}
+		line = c.Sprint(line)
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, err := fmt.Fprintln(h.w, line)
+	return err
+}
+
+func writeAttr(b *strings.Builder, a slog.Attr) {
+	if a.Equal(slog.Attr{}) {
+		return
+	}
+	fmt.Fprintf(b, " %s=%v", a.Key, a.Value.Resolve())
+}
+
+func (h *ConsoleHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	clone := *h
+	clone.attrs = append(append([]slog.Attr(nil), h.attrs...), attrs...)
+	return &clone
+}
+
+func (h *ConsoleHandler) WithGroup(string) slog.Handler {
+	return h
+}
@@ -0,0 +1,149 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
+)
+
+// Redacted replaces scrubbed values.
+const Redacted = "[REDACTED]"
+
+var (
+	// URIs are cut at whitespace and at characters that usually end them in
+	// messages, such as quotes and brackets. The scheme may be percent-encoded,
+	// once or twice, as in URIs passed in a query string.
+	uriPattern = regexp.MustCompile(`(?i)(otpauth(?:-migration)?(?:://|%(?:25)?3A%(?:25)?2F%(?:25)?2F))[^\s"'<>)\]]*`)
+	// Candidates for base32 and base64 secrets: long runs of their alphabets.
+	tokenPattern = regexp.MustCompile(`[A-Za-z0-9+/]{12,}={0,6}`)
+)
+
+// Redact removes migration URIs, otpauth URIs and anything that looks like
+// a base32 or base64 secret from s.
+//
+// Secrets are recognised by their shape, which has limits: base32 secrets
+// shorter than 16 characters and unpadded base64 without digits are kept,
+// while long runs of letters and digits that are not secrets, such as paths
+// like "/home/user/Backups2024", are redacted. Values known to be secrets
+// should not be logged at all.
+func Redact(s string) string {
+	s = uriPattern.ReplaceAllStringFunc(s, func(uri string) string {
+		scheme := uriPattern.FindStringSubmatch(uri)[1]
+		rest := uri[len(scheme):]
+		// Keep punctuation that ends the sentence rather than the URI.
+		trimmed := strings.TrimRight(rest, ".,;:")
+		return scheme + Redacted + rest[len(trimmed):]
+	})
+	return tokenPattern.ReplaceAllStringFunc(s, func(token string) string {
+		if looksLikeBase32(token) || looksLikeBase64(token) {
+			return Redacted
+		}
+		return token
+	})
+}
+
+// looksLikeBase32 accepts 16 or more characters of the base32 alphabet, in
+// upper case or with at least one digit so that long words are kept.
+func looksLikeBase32(token string) bool {
+	body := strings.TrimRight(token, "=")
+	if len(body) < 16 {
+		return false
+	}
+
+	upper, digit := true, false
+	for _, r := range body {
+		switch {
+		case r >= 'A' && r <= 'Z':
+		case r >= 'a' && r <= 'z':
+			upper = false
+		case r >= '2' && r <= '7':
+			digit = true
+		default:
+			return false
+		}
+	}
+	return upper || digit
+}
+
+// looksLikeBase64 accepts padded base64 of 16 characters or more, and
+// unpadded runs that mix upper case letters, lower case letters and digits
+// as random data does.
+func looksLikeBase64(token string) bool {
+	body := strings.TrimRight(token, "=")
+	if len(token)-len(body) > 2 || len(token) < 16 {
+		return false
+	}
+	if len(body) < len(token) {
+		return len(token)%4 == 0
+	}
+
+	var upper, lower, digit bool
+	for _, r := range body {
+		switch {
+		case r >= 'A' && r <= 'Z':
+			upper = true
+		case r >= 'a' && r <= 'z':
+			lower = true
+		case r >= '0' && r <= '9':
+			digit = true
+		}
+	}
+	return upper && lower && digit
+}
+
+// RedactingHandler scrubs messages and attribute values with Redact before
+// passing records on, so that secrets never reach the terminal or a log file.
+type RedactingHandler struct {
+	next slog.Handler
+}
+
+func NewRedactingHandler(next slog.Handler) *RedactingHandler {
+	return &RedactingHandler{next: next}
+}
+
+func (h *RedactingHandler) Enabled(ctx context.Context, l slog.Level) bool {
+	return h.next.Enabled(ctx, l)
+}
+
+func (h *RedactingHandler) Handle(ctx context.Context, r slog.Record) error {
+	clean := slog.NewRecord(r.Time, r.Level, Redact(r.Message), r.PC)
+	r.Attrs(func(a slog.Attr) bool {
+		clean.AddAttrs(redactAttr(a))
+		return true
+	})
+	return h.next.Handle(ctx, clean)
+}
+
+func (h *RedactingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	clean := make([]slog.Attr, len(attrs))
+	for i, a := range attrs {
+		clean[i] = redactAttr(a)
+	}
+	return &RedactingHandler{next: h.next.WithAttrs(clean)}
+}
+
+func (h *RedactingHandler) WithGroup(name string) slog.Handler {
+	return &RedactingHandler{next: h.next.WithGroup(name)}
+}
+
+func redactAttr(a slog.Attr) slog.Attr {
+	v := a.Value.Resolve()
+	switch v.Kind() {
+	case slog.KindString:
+		return slog.String(a.Key, Redact(v.String()))
+	case slog.KindGroup:
+		group := v.Group()
+		clean := make([]any, len(group))
+		for i, member := range group {
+			clean[i] = redactAttr(member)
+		}
+		return slog.Group(a.Key, clean...)
+	case slog.KindAny:
+		// Values such as errors and structs are formatted the way handlers
+		// would print them, then scrubbed.
+		return slog.String(a.Key, Redact(fmt.Sprint(v.Any())))
+	}
+	return slog.Attr{Key: a.Key, Value: v}
+}
@@ -0,0 +1,94 @@
+package logging
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+const (
+	migrationURI = "otpauth-migration://offline?data=CiIKCkhlbGwPId6tvugSDlRlc3QgYWNjb3VudCAxIAEoATACEAEYASAAKJ2G1g0%3D"
+	otpauthURI   = "otpauth://totp/Example:alice@example.com?secret=JBSWY3DPEHPK3PXP&issuer=Example"
+	base32Secret = "JBSWY3DPEHPK3PXP"
+	base64Secret = "SGVsbG8h3q2+7w=="
+	rawBase64    = "q83vASNFZ4mrze8BI0VniQ"
+)
+
+var secrets = []string{"CiIKCkhlbGwPId6tvugSDlRlc3Qg", "?secret", base32Secret, base64Secret, rawBase64, "jbswy3dpehpk3pxp"}
+
+func TestRedact(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"failed to decode " + migrationURI + ": bad data", "failed to decode otpauth-migration://[REDACTED]: bad data"},
+		{"URI: " + otpauthURI, "URI: otpauth://[REDACTED]"},
+		{"parse \"" + otpauthURI + "\"", "parse \"otpauth://[REDACTED]\""},
+		{"secret " + base32Secret + " and " + base64Secret, "secret [REDACTED] and [REDACTED]"},
+		{"lower case jbswy3dpehpk3pxp, raw " + rawBase64, "lower case [REDACTED], raw [REDACTED]"},
+		{"Created QR code: qrcodes/Example (alice_example.com).png", "Created QR code: qrcodes/Example (alice_example.com).png"},
+		{"Fingerprint WUQF-MBU7-53BI-LBYF of internationalization", "Fingerprint WUQF-MBU7-53BI-LBYF of internationalization"},
+		{"Saved to /home/user/backups/authenticator/accounts.json", "Saved to /home/user/backups/authenticator/accounts.json"},
+		{"GET /import?uri=otpauth-migration%3A%2F%2Foffline%3Fdata%3DCiIKCkhlbGwPId6tvugSDlRlc3Qg failed", "GET /import?uri=otpauth-migration%3A%2F%2F[REDACTED] failed"},
+		{"next=OTPAUTH%253A%252F%252Ftotp%252FExample%253Falice%253Fsecret%253DJBSWY3DP", "next=OTPAUTH%253A%252F%252F[REDACTED]"},
+		// Known limits: short base32 secrets and unpadded base64 without
+		// digits look like words, and long path components like secrets.
+		{"short secret JBSWY3DPEHPK", "short secret JBSWY3DPEHPK"},
+		{"raw base64 SGVsbGhlbGxvSGVsbA", "raw base64 SGVsbGhlbGxvSGVsbA"},
+		{"Saved to /home/user/Backups2024/accounts.json", "Saved to [REDACTED].json"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			if got := Redact(tt.input); got != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+type secretValue struct {
+	URI string
+}
+
+func TestRedactingHandler(t *testing.T) {
+	var b bytes.Buffer
+	logger := slog.New(NewRedactingHandler(slog.NewTextHandler(&b, nil))).With("uri", migrationURI)
+
+	logger.Info("decoding "+migrationURI,
+		"secret", base32Secret,
+		"err", fmt.Errorf("bad secret %s: %w", base64Secret, errors.New("invalid")),
+		"account", secretValue{URI: otpauthURI},
+		slog.Group("raw", "value", rawBase64),
+		"count", 3,
+	)
+	logger.WithGroup("g").Warn("failed", "uri", otpauthURI)
+
+	out := b.String()
+	for _, secret := range secrets {
+		if strings.Contains(out, secret) {
+			t.Errorf("Expected %q to be redacted, got:\n%s", secret, out)
+		}
+	}
+	if !strings.Contains(out, "count=3") {
+		t.Errorf("Expected other attributes to be kept, got:\n%s", out)
+	}
+}
+
+func TestPackageLoggerRedacts(t *testing.T) {
+	defer SetHandler(NewConsoleHandler(&bytes.Buffer{}, level))
+
+	var b bytes.Buffer
+	SetHandler(NewConsoleHandler(&b, level))
+	Warnf("Could not parse %s (%s)", otpauthURI, base64Secret)
+	Errorf("%v", fmt.Errorf("failed to decode URI: %s", migrationURI))
+
+	for _, secret := range secrets {
+		if strings.Contains(b.String(), secret) {
+			t.Errorf("Expected %q to be redacted, got:\n%s", secret, b.String())
+		}
+	}
+}
@@ -0,0 +1,282 @@
+package merge
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/Zaphkiel-Ivanovna/GoogleAuthExtractor/internal/decoder"
+)
+
+// Key is a property that identifies two accounts as the same.
+type Key string
+
+const (
+	// KeySecret matches accounts with the same secret bytes.
+	KeySecret Key = "secret"
+	// KeyUniqueID matches accounts with the same Google Authenticator
+	// unique ID, which survives renames on the phone.
+	KeyUniqueID Key = "unique-id"
+	// KeyFingerprint matches accounts with the same secret fingerprint, as
+	// set by decoder.SetFingerprints.
+	KeyFingerprint Key = "fingerprint"
+	// KeyLabel matches accounts with the same issuer and name, ignoring case.
+	KeyLabel Key = "label"
+)
+
+// Policy chooses which of several duplicates is kept.
+type Policy string
+
+const (
+	// PolicyNewest keeps the account from the input given last.
+	PolicyNewest Policy = "newest"
+	// PolicyCounter keeps the HOTP account with the highest counter, so that
+	// codes already used are not accepted again.
+	PolicyCounter Policy = "counter"
+	// PolicyComplete keeps the account with the most metadata filled in.
+	PolicyComplete Policy = "complete"
+)
+
+func ParseKeys(names []string) ([]Key, error) {
+	keys := make([]Key, 0, len(names))
+	for _, name := range names {
+		switch k := Key(strings.ToLower(strings.TrimSpace(name))); k {
+		case KeySecret, KeyFingerprint, KeyUniqueID, KeyLabel:
+			keys = append(keys, k)
+		default:
+			return nil, fmt.Errorf("invalid duplicate key '%s': expected secret, fingerprint, unique-id or label", name)
+		}
+	}
+	return keys, nil
+}
+
+func ParsePolicy(name string) (Policy, error) {
+	switch p := Policy(strings.ToLower(name)); p {
+	case PolicyNewest, PolicyCounter, PolicyComplete:
+		return p, nil
+	}
+	return "", fmt.Errorf("invalid merge policy '%s': expected newest, counter or complete", name)
+}
+
+// Source is the set of accounts read from one input, such as one image.
+// Sources are given oldest first.
+type Source struct {
+	Name     string
+	Accounts []decoder.Account
+}
+
+type Options struct {
+	Keys   []Key
+	Policy Policy
+}
+
+// Conflict lists accounts sharing an issuer and name but not a secret. They
+// are usually an account that was reset on one phone and not on the other.
+type Conflict struct {
+	Label   string
+	Sources []string
+}
+
+type Result struct {
+	Accounts   []decoder.Account
+	Duplicates int
+	Conflicts  []Conflict
+}
+
+type entry struct {
+	account decoder.Account
+	source  string
+	order   int
+}
+
+// Merge combines the accounts of all sources, keeping one account of each
+// group of duplicates in the position of the first one.
+func Merge(sources []Source, opts Options) Result {
+	var entries []entry
+	for _, source := range sources {
+		for _, account := range source.Accounts {
+			entries = append(entries, entry{account, source.Name, len(entries)})
+		}
+	}
+
+	keys := opts.Keys
+	if len(keys) == 0 {
+		keys = []Key{KeySecret}
+	}
+
+	groups := newUnionFind(len(entries))
+	for _, k := range keys {
+		first := make(map[string]int)
+		for i, e := range entries {
+			id := identity(e.account, k)
+			if id == "" {
+				continue
+			}
+			if j, ok := first[id]; ok {
+				groups.union(j, i)
+			} else {
+				first[id] = i
+			}
+		}
+	}
+
+	members := make(map[int][]entry)
+	var roots []int
+	for i, e := range entries {
+		root := groups.find(i)
+		if _, ok := members[root]; !ok {
+			roots = append(roots, root)
+		}
+		members[root] = append(members[root], e)
+	}
+
+	var result Result
+	for _, root := range roots {
+		group := members[root]
+		result.Accounts = append(result.Accounts, choose(group, opts.Policy).account)
+		result.Duplicates += len(group) - 1
+	}
+
+	result.Conflicts = conflicts(entries)
+	return result
+}
+
+func identity(account decoder.Account, k Key) string {
+	switch k {
+	case KeySecret:
+		return secretID(account)
+	case KeyFingerprint:
+		return account.Fingerprint
+	case KeyUniqueID:
+		return account.UniqueID
+	case KeyLabel:
+		return label(account)
+	}
+	return ""
+}
+
+// secretID compares secrets through their hash, which keeps hardened
+// accounts from turning their secret into a string.
+func secretID(account decoder.Account) string {
+	secret, err := account.SecretBytes()
+	if err != nil {
+		return account.Secret
+	}
+	sum := sha256.Sum256(secret)
+	return string(sum[:])
+}
+
+func label(account decoder.Account) string {
+	if account.Issuer == "" {
+		return strings.ToLower(account.Name)
+	}
+	return strings.ToLower(account.Issuer + ":" + account.Name)
+}
+
+func choose(group []entry, policy Policy) entry {
+	best := group[0]
+	for _, e := range group[1:] {
+		if better(e, best, policy) {
+			best = e
+		}
+	}
+	return best
+}
+
+// better reports whether a should replace b. Ties always go to the newer
+// input.
+func better(a, b entry, policy Policy) bool {
+	switch policy {
+	case PolicyCounter:
+		if a.account.Counter != b.account.Counter {
+			return a.account.Counter > b.account.Counter
+		}
+	case PolicyComplete:
+		if sa, sb := completeness(a.account), completeness(b.account); sa != sb {
+			return sa > sb
+		}
+	}
+	return a.order > b.order
+}
+
+func completeness(account decoder.Account) int {
+	score := 0
+	for _, set := range []bool{
+		account.Issuer != "",
+		account.Name != "",
+		account.UniqueID != "",
+		account.Algorithm != "" && account.Algorithm != "ALGORITHM_UNSPECIFIED",
+		account.Digits != "" && account.Digits != "DIGIT_COUNT_UNSPECIFIED",
+		account.Type != "" && account.Type != "OTP_TYPE_UNSPECIFIED",
+	} {
+		if set {
+			score++
+		}
+	}
+	return score
+}
+
+// conflicts finds labels used with more than one secret.
+func conflicts(entries []entry) []Conflict {
+	type labelInfo struct {
+		display string
+		secrets map[string]bool
+		sources []string
+	}
+
+	byLabel := make(map[string]*labelInfo)
+	var order []string
+	for _, e := range entries {
+		l := label(e.account)
+		info, ok := byLabel[l]
+		if !ok {
+			display := e.account.Name
+			if e.account.Issuer != "" {
+				display = e.account.Issuer + ":" + e.account.Name
+			}
+			info = &labelInfo{display: display, secrets: make(map[string]bool)}
+			byLabel[l] = info
+			order = append(order, l)
+		}
+		info.secrets[secretID(e.account)] = true
+		if !slices.Contains(info.sources, e.source) {
+			info.sources = append(info.sources, e.source)
+		}
+	}
+
+	var result []Conflict
+	for _, l := range order {
+		if info := byLabel[l]; len(info.secrets) > 1 {
+			result = append(result, Conflict{Label: info.display, Sources: info.sources})
+		}
+	}
+	return result
+}
+
+type unionFind []int
+
+func newUnionFind(n int) unionFind {
+	u := make(unionFind, n)
+	for i := range u {
+		u[i] = i
+	}
+	return u
+}
+
+func (u unionFind) find(i int) int {
+	for u[i] != i {
+		u[i] = u[u[i]]
+		i = u[i]
+	}
+	return i
+}
+
+func (u unionFind) union(a, b int) {
+	ra, rb := u.find(a), u.find(b)
+	if ra < rb {
+		u[rb] = ra
+	} else if rb < ra {
+		u[ra] = rb
+	}
+}
@@ -0,0 +1,118 @@
+package merge
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/Zaphkiel-Ivanovna/GoogleAuthExtractor/internal/decoder"
+)
+
+func TestMerge(t *testing.T) {
+	oldPhone := Source{Name: "old.png", Accounts: []decoder.Account{
+		{Name: "alice", Issuer: "GitHub", Secret: "AAAA", Type: "TOTP", UniqueID: "u1"},
+		{Name: "counter", Secret: "BBBB", Type: "HOTP", Counter: 9},
+		{Name: "bob", Issuer: "GitLab", Secret: "CCCC", Type: "TOTP"},
+	}}
+	newPhone := Source{Name: "new.png", Accounts: []decoder.Account{
+		{Name: "counter", Secret: "BBBB", Type: "HOTP", Counter: 3},
+		{Name: "alice (renamed)", Issuer: "GitHub", Secret: "AAAA", Type: "TOTP"},
+		{Name: "bob", Issuer: "gitlab", Secret: "DDDD", Type: "TOTP"},
+		{Name: "carol", Secret: "EEEE", Type: "TOTP", UniqueID: "u1"},
+	}}
+	for _, source := range []Source{oldPhone, newPhone} {
+		if err := decoder.SetFingerprints(source.Accounts, []byte("key")); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name       string
+		opts       Options
+		expected   []string
+		duplicates int
+	}{
+		{
+			name:       "Newest by secret",
+			opts:       Options{Policy: PolicyNewest},
+			expected:   []string{"alice (renamed)", "counter/3", "bob", "bob", "carol"},
+			duplicates: 2,
+		},
+		{
+			name:       "Highest counter",
+			opts:       Options{Policy: PolicyCounter},
+			expected:   []string{"alice (renamed)", "counter/9", "bob", "bob", "carol"},
+			duplicates: 2,
+		},
+		{
+			name:       "Most complete metadata",
+			opts:       Options{Policy: PolicyComplete},
+			expected:   []string{"alice", "counter/3", "bob", "bob", "carol"},
+			duplicates: 2,
+		},
+		{
+			name:       "Secret and unique ID",
+			opts:       Options{Keys: []Key{KeySecret, KeyUniqueID}, Policy: PolicyNewest},
+			expected:   []string{"carol", "counter/3", "bob", "bob"},
+			duplicates: 3,
+		},
+		{
+			name:       "Fingerprint",
+			opts:       Options{Keys: []Key{KeyFingerprint}, Policy: PolicyNewest},
+			expected:   []string{"alice (renamed)", "counter/3", "bob", "bob", "carol"},
+			duplicates: 2,
+		},
+		{
+			name:       "Label",
+			opts:       Options{Keys: []Key{KeyLabel}, Policy: PolicyNewest},
+			expected:   []string{"alice", "counter/3", "bob", "alice (renamed)", "carol"},
+			duplicates: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := Merge([]Source{oldPhone, newPhone}, tt.opts)
+
+			var got []string
+			for _, account := range result.Accounts {
+				name := account.Name
+				if account.Type == "HOTP" {
+					name = fmt.Sprintf("%s/%d", name, account.Counter)
+				}
+				got = append(got, name)
+			}
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+			if result.Duplicates != tt.duplicates {
+				t.Errorf("Expected %d duplicates, got %d", tt.duplicates, result.Duplicates)
+			}
+		})
+	}
+}
+
+func TestMergeConflicts(t *testing.T) {
+	result := Merge([]Source{
+		{Name: "a.png", Accounts: []decoder.Account{{Name: "bob", Issuer: "GitLab", Secret: "CCCC"}}},
+		{Name: "b.png", Accounts: []decoder.Account{{Name: "bob", Issuer: "gitlab", Secret: "DDDD"}}},
+		{Name: "c.png", Accounts: []decoder.Account{{Name: "bob", Issuer: "GitLab", Secret: "CCCC"}}},
+	}, Options{})
+
+	expected := []Conflict{{Label: "GitLab:bob", Sources: []string{"a.png", "b.png", "c.png"}}}
+	if !reflect.DeepEqual(result.Conflicts, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, result.Conflicts)
+	}
+}
+
+func TestParseOptions(t *testing.T) {
+	if _, err := ParseKeys([]string{"secret", "Unique-ID", "label"}); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if _, err := ParseKeys([]string{"name"}); err == nil {
+		t.Errorf("Expected error for unknown key")
+	}
+	if _, err := ParsePolicy("oldest"); err == nil {
+		t.Errorf("Expected error for unknown policy")
+	}
+}
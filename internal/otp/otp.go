@@ -0,0 +1,105 @@
+package otp
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/binary"
+	"fmt"
+	"hash"
+	"strings"
+	"time"
+)
+
+// Period is the TOTP time step. Google Authenticator exports do not carry a
+// period and always use 30 seconds.
+const Period = 30 * time.Second
+
+func hashFunc(algorithm string) (func() hash.Hash, error) {
+	switch strings.ToUpper(algorithm) {
+	case "", "SHA1", "ALGORITHM_UNSPECIFIED":
+		return sha1.New, nil
+	case "SHA256":
+		return sha256.New, nil
+	case "SHA512":
+		return sha512.New, nil
+	case "MD5":
+		// Dynamic truncation reads four bytes at an offset of up to 15, past
+		// the end of the 16-byte MD5 digest, so codes are not defined for it.
+		return nil, fmt.Errorf("unsupported algorithm MD5: its digest is too short for RFC 4226 codes")
+	}
+	return nil, fmt.Errorf("unsupported algorithm '%s'", algorithm)
+}
+
+// HOTP computes the RFC 4226 code for counter.
+func HOTP(secret []byte, counter uint64, digits int, algorithm string) (string, error) {
+	h, err := hashFunc(algorithm)
+	if err != nil {
+		return "", err
+	}
+
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], counter)
+
+	mac := hmac.New(h, secret)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	code := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	mod := uint32(1)
+	for i := 0; i < digits; i++ {
+		mod *= 10
+	}
+	return fmt.Sprintf("%0*d", digits, code%mod), nil
+}
+
+// TimeStep returns the TOTP counter for t.
+func TimeStep(t time.Time) uint64 {
+	return uint64(t.Unix() / int64(Period/time.Second))
+}
+
+// TOTP computes the RFC 6238 code at time t.
+func TOTP(secret []byte, t time.Time, digits int, algorithm string) (string, error) {
+	return HOTP(secret, TimeStep(t), digits, algorithm)
+}
+
+// VerifyTOTP looks for code among the time steps within window steps of now,
+// closest first. The returned offset is the number of steps between now and
+// the matching step: negative when the code belongs to the past.
+func VerifyTOTP(secret []byte, code string, now time.Time, window, digits int, algorithm string) (offset int, ok bool, err error) {
+	step := int64(TimeStep(now))
+	for distance := 0; distance <= window; distance++ {
+		for _, candidate := range []int{-distance, distance} {
+			if distance == 0 && candidate > 0 || step+int64(candidate) < 0 {
+				continue
+			}
+			want, err := HOTP(secret, uint64(step+int64(candidate)), digits, algorithm)
+			if err != nil {
+				return 0, false, err
+			}
+			if hmac.Equal([]byte(want), []byte(code)) {
+				return candidate, true, nil
+			}
+		}
+	}
+	return 0, false, nil
+}
+
+// VerifyHOTP looks for code at counter and up to lookAhead counters after it,
+// since the phone may have generated codes since the export. It returns the
+// matching counter.
+func VerifyHOTP(secret []byte, code string, counter uint64, lookAhead, digits int, algorithm string) (uint64, bool, error) {
+	for i := 0; i <= lookAhead; i++ {
+		want, err := HOTP(secret, counter+uint64(i), digits, algorithm)
+		if err != nil {
+			return 0, false, err
+		}
+		if hmac.Equal([]byte(want), []byte(code)) {
+			return counter + uint64(i), true, nil
+		}
+	}
+	return 0, false, nil
+}
@@ -0,0 +1,117 @@
+package otp
+
+import (
+	"testing"
+	"time"
+)
+
+func TestHOTP(t *testing.T) {
+	// RFC 4226, appendix D.
+	secret := []byte("12345678901234567890")
+	expected := []string{"755224", "287082", "359152", "969429", "338314", "254676", "287922", "162583", "399871", "520489"}
+
+	for counter, want := range expected {
+		got, err := HOTP(secret, uint64(counter), 6, "SHA1")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if got != want {
+			t.Errorf("Counter %d: expected %s, got %s", counter, want, got)
+		}
+	}
+}
+
+func TestHOTPMD5(t *testing.T) {
+	secret := []byte("12345678901234567890")
+	for counter := uint64(0); counter < 200; counter++ {
+		if _, err := HOTP(secret, counter, 6, "MD5"); err == nil {
+			t.Fatalf("Counter %d: expected an error for MD5", counter)
+		}
+	}
+}
+
+func TestTOTP(t *testing.T) {
+	// RFC 6238, appendix B.
+	secrets := map[string][]byte{
+		"SHA1":   []byte("12345678901234567890"),
+		"SHA256": []byte("12345678901234567890123456789012"),
+		"SHA512": []byte("1234567890123456789012345678901234567890123456789012345678901234"),
+	}
+
+	tests := []struct {
+		unix      int64
+		algorithm string
+		expected  string
+	}{
+		{59, "SHA1", "94287082"},
+		{59, "SHA256", "46119246"},
+		{59, "SHA512", "90693936"},
+		{1111111109, "SHA1", "07081804"},
+		{1234567890, "SHA256", "91819424"},
+		{2000000000, "SHA512", "38618901"},
+		{20000000000, "SHA1", "65353130"},
+	}
+
+	for _, tt := range tests {
+		got, err := TOTP(secrets[tt.algorithm], time.Unix(tt.unix, 0), 8, tt.algorithm)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if got != tt.expected {
+			t.Errorf("%s at %d: expected %s, got %s", tt.algorithm, tt.unix, tt.expected, got)
+		}
+	}
+
+	if _, err := TOTP(nil, time.Now(), 6, "SHA3"); err == nil {
+		t.Errorf("Expected error for unsupported algorithm")
+	}
+}
+
+func TestVerifyTOTP(t *testing.T) {
+	secret := []byte("12345678901234567890")
+	now := time.Unix(1111111109, 0)
+
+	tests := []struct {
+		name   string
+		at     time.Time
+		window int
+		offset int
+		ok     bool
+	}{
+		{"Current step", now, 0, 0, true},
+		{"Phone behind", now.Add(-Period), 1, -1, true},
+		{"Phone ahead", now.Add(2 * Period), 2, 2, true},
+		{"Outside window", now.Add(3 * Period), 2, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, err := TOTP(secret, tt.at, 6, "SHA1")
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			offset, ok, err := VerifyTOTP(secret, code, now, tt.window, 6, "SHA1")
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if ok != tt.ok || offset != tt.offset {
+				t.Errorf("Expected offset %d (%v), got %d (%v)", tt.offset, tt.ok, offset, ok)
+			}
+		})
+	}
+}
+
+func TestVerifyHOTP(t *testing.T) {
+	secret := []byte("12345678901234567890")
+
+	// "969429" is the code for counter 3.
+	counter, ok, err := VerifyHOTP(secret, "969429", 1, 5, 6, "SHA1")
+	if err != nil || !ok || counter != 3 {
+		t.Errorf("Expected counter 3, got %d (%v, %v)", counter, ok, err)
+	}
+
+	if _, ok, _ := VerifyHOTP(secret, "969429", 4, 5, 6, "SHA1"); ok {
+		t.Errorf("Expected codes before the counter to be rejected")
+	}
+}
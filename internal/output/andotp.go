@@ -0,0 +1,89 @@
+package output
+
+import (
+	"crypto/sha1"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+
+	"github.com/Zaphkiel-Ivanovna/GoogleAuthExtractor/internal/decoder"
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// andOTPEntry is an account in an andOTP JSON backup.
+type andOTPEntry struct {
+	Secret        string   `json:"secret"`
+	Issuer        string   `json:"issuer"`
+	Label         string   `json:"label"`
+	Digits        int      `json:"digits"`
+	Type          string   `json:"type"`
+	Algorithm     string   `json:"algorithm"`
+	Thumbnail     string   `json:"thumbnail"`
+	LastUsed      int64    `json:"last_used"`
+	UsedFrequency int      `json:"used_frequency"`
+	Period        int      `json:"period,omitempty"`
+	Counter       *int64   `json:"counter,omitempty"`
+	Tags          []string `json:"tags"`
+}
+
+// andOTP derives the key of encrypted backups with PBKDF2-HMAC-SHA1 over a
+// number of iterations stored in the file. The app picks 140000 to 160000.
+const (
+	andOTPIterations = 150000
+	andOTPSaltSize   = 12
+	andOTPNonceSize  = 12
+	andOTPKeySize    = 32
+)
+
+func andOTPEntries(accounts []decoder.Account) []andOTPEntry {
+	entries := make([]andOTPEntry, len(accounts))
+	for i, account := range accounts {
+		entry := andOTPEntry{
+			Secret:    account.Base32(),
+			Issuer:    account.Issuer,
+			Label:     account.Name,
+			Digits:    account.DigitCount(),
+			Type:      otpType(account),
+			Algorithm: account.AlgorithmOrDefault(),
+			Thumbnail: "Default",
+			Tags:      []string{},
+		}
+		if entry.Type == "HOTP" {
+			counter := account.Counter
+			entry.Counter = &counter
+		} else {
+			entry.Period = period()
+		}
+		entries[i] = entry
+	}
+	return entries
+}
+
+// encodeAndOTP returns an andOTP backup. An encrypted backup is the
+// iteration count as a 4-byte big-endian integer, the salt, the nonce and
+// the AES-256-GCM ciphertext of the plain JSON backup.
+func encodeAndOTP(accounts []decoder.Account, password []byte) ([]byte, error) {
+	data, err := json.MarshalIndent(andOTPEntries(accounts), "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal account data: %w", err)
+	}
+	if len(password) == 0 {
+		return data, nil
+	}
+
+	salt, err := randomBytes(andOTPSaltSize)
+	if err != nil {
+		return nil, err
+	}
+	key := pbkdf2.Key(password, salt, andOTPIterations, andOTPKeySize, sha1.New)
+
+	nonce, ciphertext, err := sealGCM(key, data, andOTPNonceSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt andOTP backup: %w", err)
+	}
+
+	out := binary.BigEndian.AppendUint32(nil, andOTPIterations)
+	out = append(out, salt...)
+	out = append(out, nonce...)
+	return append(out, ciphertext...), nil
+}
@@ -0,0 +1,144 @@
+package output
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Zaphkiel-Ivanovna/GoogleAuthExtractor/internal/decoder"
+	"github.com/Zaphkiel-Ivanovna/GoogleAuthExtractor/internal/logging"
+	"github.com/Zaphkiel-Ivanovna/GoogleAuthExtractor/internal/otp"
+)
+
+// BackupFormat is the backup file format of another authenticator app.
+type BackupFormat string
+
+const (
+	BackupAndOTP  BackupFormat = "andotp"
+	Backup2FAS    BackupFormat = "2fas"
+	BackupFreeOTP BackupFormat = "freeotp"
+	BackupEnte    BackupFormat = "ente"
+)
+
+func ParseBackupFormat(s string) (BackupFormat, error) {
+	switch format := BackupFormat(strings.ToLower(s)); format {
+	case BackupAndOTP, Backup2FAS, BackupFreeOTP, BackupEnte:
+		return format, nil
+	}
+	return "", fmt.Errorf("unknown backup format '%s' (expected andotp, 2fas, freeotp or ente)", s)
+}
+
+// Encrypts reports whether the app can import the format encrypted.
+func (f BackupFormat) Encrypts() bool {
+	return f == BackupAndOTP || f == Backup2FAS
+}
+
+// Extension returns the file name extension the app expects for a backup,
+// encrypted or not.
+func (f BackupFormat) Extension(encrypted bool) string {
+	switch {
+	case f == BackupAndOTP && encrypted:
+		return ".json.aes"
+	case f == BackupAndOTP, f == BackupFreeOTP:
+		return ".json"
+	case f == BackupEnte:
+		return ".txt"
+	}
+	return ".2fas"
+}
+
+// SupportsMD5 reports whether the app can import accounts using MD5.
+func (f BackupFormat) SupportsMD5() bool {
+	return f == BackupFreeOTP || f == BackupEnte
+}
+
+// EncodeBackup returns the accounts as a backup in format, encrypted with
+// password unless it is empty.
+func EncodeBackup(accounts []decoder.Account, format BackupFormat, password []byte) ([]byte, error) {
+	if len(password) > 0 && !format.Encrypts() {
+		return nil, fmt.Errorf("%s backups cannot be encrypted", format)
+	}
+	if !format.SupportsMD5() {
+		for _, account := range accounts {
+			if account.AlgorithmOrDefault() == "MD5" {
+				return nil, fmt.Errorf("account '%s' uses MD5, which %s does not support; leave it out with --pick or --edit-rules", account.Name, format)
+			}
+		}
+	}
+
+	switch format {
+	case BackupAndOTP:
+		return encodeAndOTP(accounts, password)
+	case Backup2FAS:
+		return encode2FAS(accounts, password, time.Now())
+	case BackupFreeOTP:
+		return encodeFreeOTP(accounts)
+	case BackupEnte:
+		return encodeEnte(accounts), nil
+	}
+	return nil, fmt.Errorf("unknown backup format '%s'", format)
+}
+
+func SaveBackup(accounts []decoder.Account, filename string, format BackupFormat, password []byte) error {
+
+	data, err := EncodeBackup(accounts, format, password)
+	if err != nil {
+		return err
+	}
+
+	written, err := WriteFile(filename, data)
+	if err != nil || written == "" {
+		return err
+	}
+
+	if len(password) > 0 {
+		logging.Successf("Successfully saved %d accounts to encrypted %s backup %s", len(accounts), format, written)
+	} else {
+		logging.Successf("Successfully saved %d accounts to %s backup %s", len(accounts), format, written)
+	}
+	return nil
+}
+
+// otpType returns "TOTP" or "HOTP" for the account.
+func otpType(account decoder.Account) string {
+	if account.Type == "HOTP" {
+		return "HOTP"
+	}
+	return "TOTP"
+}
+
+// period returns the TOTP period in seconds, which Google Authenticator
+// exports never change from the default.
+func period() int {
+	return int(otp.Period / time.Second)
+}
+
+// sealGCM encrypts plaintext with AES-GCM under key and a new random nonce
+// of nonceSize bytes. The authentication tag is appended to the ciphertext.
+func sealGCM(key, plaintext []byte, nonceSize int) (nonce, ciphertext []byte, err error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	gcm, err := cipher.NewGCMWithNonceSize(block, nonceSize)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	nonce, err = randomBytes(nonceSize)
+	if err != nil {
+		return nil, nil, err
+	}
+	return nonce, gcm.Seal(nil, nonce, plaintext, nil), nil
+}
+
+func randomBytes(n int) ([]byte, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return nil, fmt.Errorf("failed to generate random bytes: %w", err)
+	}
+	return b, nil
+}
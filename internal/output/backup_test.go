@@ -0,0 +1,199 @@
+package output
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Zaphkiel-Ivanovna/GoogleAuthExtractor/internal/decoder"
+	"golang.org/x/crypto/pbkdf2"
+)
+
+var backupAccounts = []decoder.Account{
+	{Name: "alice@example.com", Issuer: "Example", TOTPSecret: "JBSWY3DPEHPK3PXP", Type: "TOTP", Algorithm: "SHA256", Digits: "EIGHT"},
+	{Name: "counter", TOTPSecret: "GEZDGNBVGY3TQOJQ", Type: "HOTP", Algorithm: "ALGORITHM_UNSPECIFIED", Digits: "SIX", Counter: 7},
+}
+
+func openGCM(t *testing.T, key, nonce, ciphertext []byte) []byte {
+	t.Helper()
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gcm, err := cipher.NewGCMWithNonceSize(block, len(nonce))
+	if err != nil {
+		t.Fatal(err)
+	}
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		t.Fatalf("Failed to decrypt backup: %v", err)
+	}
+	return plaintext
+}
+
+func TestAndOTPBackup(t *testing.T) {
+	password := []byte("correct horse")
+
+	data, err := EncodeBackup(backupAccounts, BackupAndOTP, password)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	iterations := int(binary.BigEndian.Uint32(data))
+	salt, nonce := data[4:16], data[16:28]
+	key := pbkdf2.Key(password, salt, iterations, 32, sha1.New)
+
+	var entries []map[string]any
+	if err := json.Unmarshal(openGCM(t, key, nonce, data[28:]), &entries); err != nil {
+		t.Fatalf("Failed to parse decrypted backup: %v", err)
+	}
+
+	expected := []map[string]any{
+		{"secret": "JBSWY3DPEHPK3PXP", "issuer": "Example", "label": "alice@example.com", "digits": 8.0, "type": "TOTP", "algorithm": "SHA256", "period": 30.0},
+		{"secret": "GEZDGNBVGY3TQOJQ", "issuer": "", "label": "counter", "digits": 6.0, "type": "HOTP", "algorithm": "SHA1", "counter": 7.0},
+	}
+	for i, fields := range expected {
+		for key, value := range fields {
+			if entries[i][key] != value {
+				t.Errorf("Expected %s of entry %d to be %v, got %v", key, i, value, entries[i][key])
+			}
+		}
+	}
+	if _, ok := entries[1]["period"]; ok {
+		t.Errorf("Expected no period for an HOTP entry")
+	}
+}
+
+func Test2FASBackup(t *testing.T) {
+	password := []byte("correct horse")
+	now := time.UnixMilli(1700000000000)
+
+	plain, err := encode2FAS(backupAccounts, nil, now)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	encrypted, err := encode2FAS(backupAccounts, password, now)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var plainBackup, encryptedBackup twoFASBackup
+	if err := json.Unmarshal(plain, &plainBackup); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(encrypted, &encryptedBackup); err != nil {
+		t.Fatal(err)
+	}
+	if len(encryptedBackup.Services) != 0 {
+		t.Errorf("Expected no plain services in an encrypted backup")
+	}
+
+	open := func(field string) []byte {
+		parts := strings.Split(field, ":")
+		if len(parts) != 3 {
+			t.Fatalf("Expected ciphertext:salt:nonce, got %q", field)
+		}
+		var raw [3][]byte
+		for i, part := range parts {
+			if raw[i], err = base64.StdEncoding.DecodeString(part); err != nil {
+				t.Fatal(err)
+			}
+		}
+		key := pbkdf2.Key(password, raw[1], 10000, 32, sha256.New)
+		return openGCM(t, key, raw[2], raw[0])
+	}
+
+	if reference := open(encryptedBackup.Reference); string(reference) != twoFASReference {
+		t.Errorf("Expected the reference text, got %q", reference)
+	}
+
+	var services []twoFASService
+	if err := json.Unmarshal(open(encryptedBackup.ServicesEncrypted), &services); err != nil {
+		t.Fatalf("Failed to parse decrypted services: %v", err)
+	}
+	if len(services) != 2 || services[0] != plainBackup.Services[0] || services[1] != plainBackup.Services[1] {
+		t.Errorf("Expected the decrypted services to match the plain ones, got %+v", services)
+	}
+
+	expected := twoFASOTP{Label: "counter", Account: "counter", Digits: 6, Period: 30, Algorithm: "SHA1", Counter: 7, TokenType: "HOTP", Source: "Link"}
+	if got := plainBackup.Services[1]; got.Name != "counter" || got.OTP != expected || got.Order.Position != 1 {
+		t.Errorf("Expected %+v, got %+v", expected, got)
+	}
+}
+
+func TestFreeOTPBackup(t *testing.T) {
+	accounts := []decoder.Account{
+		{Name: "alice", Issuer: "Example", Secret: "/wABgA==", Type: "TOTP", Digits: "SEVEN"},
+		{Name: "counter", Secret: "AQI=", Type: "HOTP", Algorithm: "SHA512", Counter: 42},
+	}
+
+	data, err := EncodeBackup(accounts, BackupFreeOTP, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var backup freeOTPBackup
+	if err := json.Unmarshal(data, &backup); err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := []string{"Example:alice", "counter"}; !reflect.DeepEqual(backup.TokenOrder, expected) {
+		t.Errorf("Expected token order %q, got %q", expected, backup.TokenOrder)
+	}
+	expected := []freeOTPToken{
+		{Algo: "SHA1", Digits: 7, IssuerExt: "Example", IssuerInt: "Example", Label: "alice", Period: 30, Secret: []int8{-1, 0, 1, -128}, Type: "TOTP"},
+		{Algo: "SHA512", Counter: 42, Digits: 6, Label: "counter", Period: 30, Secret: []int8{1, 2}, Type: "HOTP"},
+	}
+	if !reflect.DeepEqual(backup.Tokens, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, backup.Tokens)
+	}
+}
+
+func TestEnteBackup(t *testing.T) {
+	data, err := EncodeBackup(backupAccounts, BackupEnte, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := "otpauth://totp/Example:alice@example.com?algorithm=SHA256&digits=8&issuer=Example&period=30&secret=JBSWY3DPEHPK3PXP\n" +
+		"otpauth://hotp/counter?algorithm=SHA1&counter=7&digits=6&secret=GEZDGNBVGY3TQOJQ\n"
+	if string(data) != expected {
+		t.Errorf("Expected %q, got %q", expected, data)
+	}
+
+	if _, err := EncodeBackup(backupAccounts, BackupEnte, []byte("secret")); err == nil {
+		t.Errorf("Expected error when encrypting an Ente backup")
+	}
+}
+
+func TestBackupMD5(t *testing.T) {
+	accounts := []decoder.Account{{Name: "legacy", TOTPSecret: "JBSWY3DPEHPK3PXP", Type: "TOTP", Algorithm: "MD5"}}
+
+	tests := []struct {
+		format    BackupFormat
+		expectErr bool
+	}{
+		{BackupAndOTP, true},
+		{Backup2FAS, true},
+		{BackupFreeOTP, false},
+		{BackupEnte, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.format), func(t *testing.T) {
+			_, err := EncodeBackup(accounts, tt.format, nil)
+			if (err != nil) != tt.expectErr {
+				t.Errorf("Expected error to be %v, got: %v", tt.expectErr, err)
+			}
+		})
+	}
+}
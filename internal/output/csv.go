@@ -0,0 +1,210 @@
+package output
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/Zaphkiel-Ivanovna/GoogleAuthExtractor/internal/decoder"
+	"github.com/Zaphkiel-Ivanovna/GoogleAuthExtractor/internal/logging"
+)
+
+// Column is a column of a CSV or TSV export.
+type Column string
+
+const (
+	ColumnIssuer      Column = "issuer"
+	ColumnName        Column = "name"
+	ColumnType        Column = "type"
+	ColumnAlgorithm   Column = "algorithm"
+	ColumnDigits      Column = "digits"
+	ColumnCounter     Column = "counter"
+	ColumnSecret      Column = "secret"
+	ColumnURI         Column = "uri"
+	ColumnFingerprint Column = "fingerprint"
+)
+
+var columns = []Column{ColumnIssuer, ColumnName, ColumnType, ColumnAlgorithm, ColumnDigits, ColumnCounter, ColumnSecret, ColumnURI, ColumnFingerprint}
+
+// DefaultColumns leave the secret out, so that an inventory can be shared
+// without giving away the accounts. The fingerprint column is only safe to
+// share when the fingerprints were made with a secret key, so callers drop it
+// when accounts have none.
+var DefaultColumns = []Column{ColumnIssuer, ColumnName, ColumnType, ColumnAlgorithm, ColumnDigits, ColumnCounter, ColumnFingerprint}
+
+func ParseColumns(names []string) ([]Column, error) {
+	var parsed []Column
+	for _, name := range names {
+		column := Column(strings.ToLower(strings.TrimSpace(name)))
+		if !slices.Contains(columns, column) {
+			return nil, fmt.Errorf("unknown column '%s' (expected issuer, name, type, algorithm, digits, counter, secret, uri or fingerprint)", name)
+		}
+		parsed = append(parsed, column)
+	}
+	if len(parsed) == 0 {
+		return nil, fmt.Errorf("no columns selected")
+	}
+	return parsed, nil
+}
+
+// SecretPolicy decides how the secret and uri columns are written.
+type SecretPolicy string
+
+const (
+	// SecretsPlain writes the secrets.
+	SecretsPlain SecretPolicy = "plain"
+	// SecretsFingerprint writes the secret's fingerprint instead.
+	SecretsFingerprint SecretPolicy = "fingerprint"
+	// SecretsOmit drops the secret and uri columns.
+	SecretsOmit SecretPolicy = "omit"
+)
+
+func ParseSecretPolicy(s string) (SecretPolicy, error) {
+	switch policy := SecretPolicy(strings.ToLower(s)); policy {
+	case SecretsPlain, SecretsFingerprint, SecretsOmit:
+		return policy, nil
+	}
+	return "", fmt.Errorf("unknown secret policy '%s' (expected plain, fingerprint or omit)", s)
+}
+
+// TableOptions configures a CSV or TSV export.
+type TableOptions struct {
+	Columns []Column
+	Secrets SecretPolicy
+	// TSV separates fields with tabs instead of commas.
+	TSV bool
+}
+
+// NeedsFingerprints reports whether the table shows fingerprints, which
+// accounts only have when a fingerprint key is configured.
+func (o TableOptions) NeedsFingerprints() bool {
+	if slices.Contains(o.Columns, ColumnFingerprint) {
+		return true
+	}
+	return o.Secrets == SecretsFingerprint && (slices.Contains(o.Columns, ColumnSecret) || slices.Contains(o.Columns, ColumnURI))
+}
+
+// EncodeTable returns the accounts as CSV following RFC 4180, with CRLF
+// line endings and fields quoted when needed, or as TSV. The first row
+// holds the column names.
+func EncodeTable(accounts []decoder.Account, opts TableOptions) ([]byte, error) {
+	cols := opts.Columns
+	if opts.Secrets == SecretsOmit {
+		cols = nil
+		for _, column := range opts.Columns {
+			if column != ColumnSecret && column != ColumnURI {
+				cols = append(cols, column)
+			}
+		}
+		if len(cols) == 0 {
+			return nil, fmt.Errorf("no columns left once secrets are omitted")
+		}
+	}
+
+	if opts.NeedsFingerprints() {
+		for _, account := range accounts {
+			if account.Fingerprint == "" {
+				return nil, fmt.Errorf("'%s' has no fingerprint: a fingerprint key is needed", account.Name)
+			}
+		}
+	}
+
+	var b bytes.Buffer
+	w := csv.NewWriter(&b)
+	if opts.TSV {
+		w.Comma = '\t'
+	} else {
+		w.UseCRLF = true
+	}
+
+	header := make([]string, len(cols))
+	for i, column := range cols {
+		header[i] = string(column)
+	}
+	w.Write(header)
+
+	for _, account := range accounts {
+		record := make([]string, len(cols))
+		for i, column := range cols {
+			record[i] = escapeFormula(tableValue(account, column, opts.Secrets))
+		}
+		w.Write(record)
+	}
+
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return nil, fmt.Errorf("failed to write table: %w", err)
+	}
+	return b.Bytes(), nil
+}
+
+func tableValue(account decoder.Account, column Column, secrets SecretPolicy) string {
+	switch column {
+	case ColumnIssuer:
+		return account.Issuer
+	case ColumnName:
+		return account.Name
+	case ColumnType:
+		return otpType(account)
+	case ColumnAlgorithm:
+		return account.AlgorithmOrDefault()
+	case ColumnDigits:
+		return strconv.Itoa(account.DigitCount())
+	case ColumnCounter:
+		if otpType(account) != "HOTP" {
+			return ""
+		}
+		return strconv.FormatInt(account.Counter, 10)
+	case ColumnSecret, ColumnURI:
+		if secrets == SecretsFingerprint {
+			return account.Fingerprint
+		}
+		if column == ColumnURI {
+			return generateOtpAuthURI(account)
+		}
+		return account.Base32()
+	case ColumnFingerprint:
+		return account.Fingerprint
+	}
+	return ""
+}
+
+// escapeFormula prefixes values that spreadsheets would run as formulas,
+// such as an issuer of "=HYPERLINK(...)", with a quote.
+func escapeFormula(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
+
+func SaveToTable(accounts []decoder.Account, filename string, opts TableOptions) error {
+
+	data, err := EncodeTable(accounts, opts)
+	if err != nil {
+		return err
+	}
+
+	written, err := WriteFile(filename, data)
+	if err != nil || written == "" {
+		return err
+	}
+
+	logging.Successf("Successfully saved %d accounts to %s", len(accounts), written)
+	return nil
+}
+
+func PrintTable(accounts []decoder.Account, opts TableOptions) error {
+
+	data, err := EncodeTable(accounts, opts)
+	if err != nil {
+		return err
+	}
+
+	_, err = os.Stdout.Write(data)
+	return err
+}
@@ -0,0 +1,84 @@
+package output
+
+import (
+	"testing"
+
+	"github.com/Zaphkiel-Ivanovna/GoogleAuthExtractor/internal/decoder"
+)
+
+func TestEncodeTable(t *testing.T) {
+	accounts := []decoder.Account{
+		{Name: "Doe, Jane", Issuer: `ACME "Corp"`, Secret: "AAAA", TOTPSecret: "AAAA", Type: "TOTP", Digits: "EIGHT"},
+		{Name: "runner", Issuer: "=HYPERLINK(\"x\")", Secret: "BBBB", TOTPSecret: "BBBB", Type: "HOTP", Algorithm: "SHA256", Counter: 5},
+	}
+	if err := decoder.SetFingerprints(accounts, []byte("key")); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		opts     TableOptions
+		expected string
+	}{
+		{
+			"CSV",
+			TableOptions{Columns: []Column{ColumnIssuer, ColumnName, ColumnDigits, ColumnCounter, ColumnSecret}, Secrets: SecretsPlain},
+			"issuer,name,digits,counter,secret\r\n" +
+				`"ACME ""Corp""","Doe, Jane",8,,AAAA` + "\r\n" +
+				`"'=HYPERLINK(""x"")",runner,6,5,BBBB` + "\r\n",
+		},
+		{
+			"TSV",
+			TableOptions{Columns: []Column{ColumnName, ColumnType, ColumnAlgorithm}, Secrets: SecretsPlain, TSV: true},
+			"name\ttype\talgorithm\nDoe, Jane\tTOTP\tSHA1\nrunner\tHOTP\tSHA256\n",
+		},
+		{
+			"Fingerprinted secrets",
+			TableOptions{Columns: []Column{ColumnName, ColumnSecret}, Secrets: SecretsFingerprint},
+			"name,secret\r\n\"Doe, Jane\"," + accounts[0].Fingerprint + "\r\nrunner," + accounts[1].Fingerprint + "\r\n",
+		},
+		{
+			"Omitted secrets",
+			TableOptions{Columns: []Column{ColumnName, ColumnSecret, ColumnURI}, Secrets: SecretsOmit},
+			"name\r\n\"Doe, Jane\"\r\nrunner\r\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := EncodeTable(accounts, tt.opts)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if string(data) != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, data)
+			}
+		})
+	}
+}
+
+func TestEncodeTableWithoutFingerprints(t *testing.T) {
+	accounts := []decoder.Account{{Name: "alice", Secret: "AAAA", TOTPSecret: "AAAA"}}
+
+	for _, opts := range []TableOptions{
+		{Columns: []Column{ColumnName, ColumnFingerprint}, Secrets: SecretsPlain},
+		{Columns: []Column{ColumnName, ColumnURI}, Secrets: SecretsFingerprint},
+	} {
+		if _, err := EncodeTable(accounts, opts); err == nil {
+			t.Errorf("Expected an error for %v without fingerprints", opts.Columns)
+		}
+	}
+
+	if _, err := EncodeTable(accounts, TableOptions{Columns: []Column{ColumnName, ColumnSecret}, Secrets: SecretsOmit}); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestParseColumns(t *testing.T) {
+	if _, err := ParseColumns([]string{"issuer", "password"}); err == nil {
+		t.Errorf("Expected error for an unknown column")
+	}
+	if got, err := ParseColumns([]string{" Issuer", "URI"}); err != nil || len(got) != 2 || got[1] != ColumnURI {
+		t.Errorf("Expected [issuer uri], got %v (%v)", got, err)
+	}
+}
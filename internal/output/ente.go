@@ -0,0 +1,47 @@
+package output
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/Zaphkiel-Ivanovna/GoogleAuthExtractor/internal/decoder"
+)
+
+// encodeEnte returns the plain text list of otpauth URIs that Ente Auth
+// imports, one account per line.
+func encodeEnte(accounts []decoder.Account) []byte {
+	var b strings.Builder
+	for _, account := range accounts {
+		b.WriteString(enteURI(account))
+		b.WriteByte('\n')
+	}
+	return []byte(b.String())
+}
+
+// enteURI returns an otpauth URI with every parameter spelled out, as Ente
+// Auth writes them, so that nothing relies on the importer's defaults.
+func enteURI(account decoder.Account) string {
+	label := account.Name
+	if account.Issuer != "" {
+		label = account.Issuer + ":" + account.Name
+	}
+
+	params := url.Values{}
+	params.Set("secret", account.Base32())
+	if account.Issuer != "" {
+		params.Set("issuer", account.Issuer)
+	}
+	params.Set("algorithm", account.AlgorithmOrDefault())
+	params.Set("digits", strconv.Itoa(account.DigitCount()))
+	if otpType(account) == "HOTP" {
+		params.Set("counter", strconv.FormatInt(account.Counter, 10))
+	} else {
+		params.Set("period", strconv.Itoa(period()))
+	}
+
+	// url.Values encodes spaces as "+", which otpauth parsers read literally.
+	query := strings.ReplaceAll(params.Encode(), "+", "%20")
+	return fmt.Sprintf("otpauth://%s/%s?%s", strings.ToLower(otpType(account)), url.PathEscape(label), query)
+}
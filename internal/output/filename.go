@@ -0,0 +1,205 @@
+package output
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/Zaphkiel-Ivanovna/GoogleAuthExtractor/internal/decoder"
+	"golang.org/x/text/unicode/norm"
+)
+
+// DefaultNameTemplate names files "Issuer (name)", as earlier versions did.
+const DefaultNameTemplate = `{{or .Issuer "No_Issuer"}} ({{.Name}})`
+
+// NameFields are the values available to a name template.
+type NameFields struct {
+	Issuer string
+	Name   string
+	// Index is the 1-based position of the account in the export.
+	Index int
+	// Type is "totp" or "hotp".
+	Type string
+	// Hash is the start of the secret's fingerprint, which tells apart
+	// accounts with the same label without revealing the secret. Accounts
+	// without a fingerprint are fingerprinted with a random key, so their
+	// Hash changes from run to run.
+	Hash string
+}
+
+// NameTemplate builds the names of per-account output files. A "/" in the
+// template text separates subdirectories.
+type NameTemplate struct {
+	tmpl *template.Template
+}
+
+var nameFuncs = template.FuncMap{
+	"lower": strings.ToLower,
+	"upper": strings.ToUpper,
+	"trunc": func(n int, s string) string {
+		if r := []rune(s); len(r) > n {
+			return string(r[:n])
+		}
+		return s
+	},
+}
+
+func ParseNameTemplate(text string) (*NameTemplate, error) {
+	tmpl, err := template.New("name").Funcs(nameFuncs).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid name template: %w", err)
+	}
+
+	t := &NameTemplate{tmpl}
+	if _, err := t.execute(NameFields{Issuer: "Issuer", Name: "name", Index: 1, Type: "totp", Hash: "0123abcd"}); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// FileNames is the template used for per-account output files.
+var FileNames, _ = ParseNameTemplate(DefaultNameTemplate)
+
+func (t *NameTemplate) execute(fields NameFields) (string, error) {
+	var b bytes.Buffer
+	if err := t.tmpl.Execute(&b, fields); err != nil {
+		return "", fmt.Errorf("invalid name template: %w", err)
+	}
+	return b.String(), nil
+}
+
+// pathSeparators keeps slashes in labels, as in "user/admin", from creating
+// directories: only those written in the template do.
+var pathSeparators = strings.NewReplacer("/", "_", "\\", "_")
+
+func nameFields(account decoder.Account, index int, key []byte) NameFields {
+	fingerprint := account.Fingerprint
+	if fingerprint == "" {
+		fingerprint, _ = account.KeyedFingerprint(key)
+	}
+	hash := strings.ToLower(strings.ReplaceAll(fingerprint, "-", ""))
+	if len(hash) > 8 {
+		hash = hash[:8]
+	}
+
+	return NameFields{
+		Issuer: pathSeparators.Replace(account.Issuer),
+		Name:   pathSeparators.Replace(account.Name),
+		Index:  index,
+		Type:   strings.ToLower(account.Type),
+		Hash:   hash,
+	}
+}
+
+// accountFileNames returns one file name per account in directory, built
+// with FileNames and ending with ext. Each path element is sanitised, and
+// names that would collide, ignoring case as Windows and macOS do, are
+// numbered.
+func accountFileNames(accounts []decoder.Account, directory, ext string) ([]string, error) {
+	key, err := decoder.NewFingerprintKey()
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, len(accounts))
+	used := make(map[string]bool)
+
+	for i, account := range accounts {
+		rendered, err := FileNames.execute(nameFields(account, i+1, key))
+		if err != nil {
+			return nil, err
+		}
+
+		var parts []string
+		for _, part := range strings.FieldsFunc(rendered, func(r rune) bool { return r == '/' || r == '\\' }) {
+			parts = append(parts, sanitizeFilename(part))
+		}
+		if len(parts) == 0 {
+			parts = []string{"_"}
+		}
+		base := filepath.Join(parts...)
+
+		name := base + ext
+		for n := 2; used[strings.ToLower(name)]; n++ {
+			name = fmt.Sprintf("%s (%d)%s", base, n, ext)
+		}
+		used[strings.ToLower(name)] = true
+		names[i] = filepath.Join(directory, name)
+	}
+	return names, nil
+}
+
+// Longest file name kept, in bytes, leaving room for an extension and a
+// collision number within the usual limit of 255.
+const maxFilenameBytes = 200
+
+// sanitizeFilename makes s safe to use as a single file name on Linux,
+// macOS and Windows.
+func sanitizeFilename(s string) string {
+	s = strings.ToValidUTF8(s, "_")
+	// macOS stores names decomposed; composing them keeps names typed on
+	// different systems equal.
+	s = norm.NFC.String(s)
+
+	s = strings.Map(func(r rune) rune {
+		switch {
+		case strings.ContainsRune(`\/:*?"<>|%&{}$+!'=@`, r):
+			return '_'
+		case unicode.IsControl(r), unicode.Is(unicode.Cf, r):
+			// Control characters and invisible formatting characters, such as
+			// the right-to-left override that can disguise an extension.
+			return '_'
+		}
+		return r
+	}, s)
+
+	s = strings.TrimLeft(s, " ")
+	s = strings.TrimRight(s, " .")
+
+	s = strings.TrimRight(truncateFilename(s), " .")
+
+	if s == "" || s == "." || s == ".." {
+		return "_"
+	}
+	if isReservedWindowsName(s) {
+		s = "_" + s
+	}
+	return s
+}
+
+// truncateFilename cuts s to maxFilenameBytes without splitting a
+// character.
+func truncateFilename(s string) string {
+	if len(s) <= maxFilenameBytes {
+		return s
+	}
+	cut := maxFilenameBytes
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return s[:cut]
+}
+
+// isReservedWindowsName reports whether name is a device name that Windows
+// does not allow as a file name, with or without an extension.
+func isReservedWindowsName(name string) bool {
+	stem := strings.ToUpper(name)
+	if i := strings.IndexByte(stem, '.'); i >= 0 {
+		stem = stem[:i]
+	}
+	stem = strings.TrimRight(stem, " ")
+
+	switch stem {
+	case "CON", "PRN", "AUX", "NUL", "CONIN$", "CONOUT$":
+		return true
+	}
+	if len(stem) >= 4 && (strings.HasPrefix(stem, "COM") || strings.HasPrefix(stem, "LPT")) {
+		suffix := stem[3:]
+		return len(suffix) == 1 && suffix[0] >= '0' && suffix[0] <= '9' ||
+			suffix == "¹" || suffix == "²" || suffix == "³"
+	}
+	return false
+}
@@ -0,0 +1,147 @@
+package output
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Zaphkiel-Ivanovna/GoogleAuthExtractor/internal/decoder"
+)
+
+func TestSanitizeFilename(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"alice@example.com", "alice_example.com"},
+		{`a/b\c:d*e?f"g<h>i|j`, "a_b_c_d_e_f_g_h_i_j"},
+		{"  trailing dots... ", "trailing dots"},
+		{"", "_"},
+		{"..", "_"},
+		{"CON", "_CON"},
+		{"nul.txt", "_nul.txt"},
+		{"com1", "_com1"},
+		{"LPT²", "_LPT²"},
+		{"Console", "Console"},
+		{"COM10", "COM10"},
+		{"line\nbreak\x00", "line_break_"},
+		{"invoice\u202egnp.exe", "invoice_gnp.exe"},
+		{"Cafe\u0301", "Caf\u00e9"},
+		{"bad\xffutf8", "bad_utf8"},
+		{strings.Repeat("é", 150), strings.Repeat("é", 100)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			if got := sanitizeFilename(tt.input); got != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestAccountFileNames(t *testing.T) {
+	defer func(t *NameTemplate) { FileNames = t }(FileNames)
+
+	accounts := []decoder.Account{
+		{Name: "alice", Issuer: "GitHub", Secret: "AAAA", Type: "TOTP"},
+		{Name: "Alice", Issuer: "github", Secret: "BBBB", Type: "TOTP"},
+		{Name: "bob", Secret: "CCCC", Type: "HOTP"},
+		{Name: "alice", Issuer: "GitHub", Secret: "DDDD", Type: "TOTP"},
+	}
+
+	tests := []struct {
+		template string
+		expected []string
+	}{
+		{DefaultNameTemplate, []string{"GitHub (alice).png", "github (Alice) (2).png", "No_Issuer (bob).png", "GitHub (alice) (3).png"}},
+		{"{{.Index}}-{{.Type}}", []string{"1-totp.png", "2-totp.png", "3-hotp.png", "4-totp.png"}},
+		{"{{lower .Issuer}}/{{.Name}}", []string{"github/alice.png", "github/Alice (2).png", "bob.png", "github/alice (3).png"}},
+		{"../{{.Name}}", []string{"_/alice.png", "_/Alice (2).png", "_/bob.png", "_/alice (3).png"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.template, func(t *testing.T) {
+			var err error
+			if FileNames, err = ParseNameTemplate(tt.template); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			names, err := accountFileNames(accounts, "out", ".png")
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			for i, name := range tt.expected {
+				tt.expected[i] = filepath.Join("out", filepath.FromSlash(name))
+			}
+			if !reflect.DeepEqual(names, tt.expected) {
+				t.Errorf("Expected %q, got %q", tt.expected, names)
+			}
+		})
+	}
+}
+
+func TestAccountFileNamesWithSlashes(t *testing.T) {
+	defer func(t *NameTemplate) { FileNames = t }(FileNames)
+
+	accounts := []decoder.Account{
+		{Name: "user/admin", Issuer: "GitHub", Secret: "AAAA", Type: "TOTP"},
+		{Name: "bob", Issuer: `ACME\EU`, Secret: "BBBB", Type: "TOTP"},
+	}
+
+	tests := []struct {
+		template string
+		expected []string
+	}{
+		{DefaultNameTemplate, []string{"GitHub (user_admin).png", "ACME_EU (bob).png"}},
+		{"{{.Issuer}}/{{.Name}}", []string{"GitHub/user_admin.png", "ACME_EU/bob.png"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.template, func(t *testing.T) {
+			var err error
+			if FileNames, err = ParseNameTemplate(tt.template); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			names, err := accountFileNames(accounts, "out", ".png")
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			for i, name := range tt.expected {
+				tt.expected[i] = filepath.Join("out", filepath.FromSlash(name))
+			}
+			if !reflect.DeepEqual(names, tt.expected) {
+				t.Errorf("Expected %q, got %q", tt.expected, names)
+			}
+		})
+	}
+}
+
+func TestNameHash(t *testing.T) {
+	a := nameFields(decoder.Account{Secret: "AAAA"}, 1, []byte("key")).Hash
+	b := nameFields(decoder.Account{Secret: "BBBB"}, 1, []byte("key")).Hash
+
+	if len(a) != 8 || a == b {
+		t.Errorf("Expected distinct 8-character hashes, got %q and %q", a, b)
+	}
+
+	unkeyed, _ := decoder.Account{Secret: "AAAA"}.KeyedFingerprint(nil)
+	if strings.HasPrefix(strings.ReplaceAll(unkeyed, "-", ""), strings.ToUpper(a)) {
+		t.Errorf("Expected the hash to depend on the key, got %q", a)
+	}
+
+	fingerprinted := decoder.Account{Secret: "AAAA", Fingerprint: "WUQF-MBU7-53BI-LBYF"}
+	if got := nameFields(fingerprinted, 1, []byte("key")).Hash; got != "wuqfmbu7" {
+		t.Errorf("Expected the hash to come from the fingerprint, got %q", got)
+	}
+}
+
+func TestParseNameTemplate(t *testing.T) {
+	for _, text := range []string{"{{.Name", "{{.Secret}}", "{{nope .Name}}"} {
+		if _, err := ParseNameTemplate(text); err == nil {
+			t.Errorf("Expected error for template %q", text)
+		}
+	}
+}
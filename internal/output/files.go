@@ -0,0 +1,283 @@
+package output
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/Zaphkiel-Ivanovna/GoogleAuthExtractor/internal/logging"
+)
+
+var ErrUnsafeDirectory = errors.New("refusing to write secrets to this directory")
+
+// FileMode is the permission mode of files written by the exporters.
+// Directories they create get the matching search (x) bits.
+var FileMode os.FileMode = 0600
+
+// ClobberPolicy decides what happens when an output file already exists.
+type ClobberPolicy string
+
+const (
+	// ClobberRefuse fails with ErrFileExists.
+	ClobberRefuse ClobberPolicy = "refuse"
+	// ClobberOverwrite replaces the existing file.
+	ClobberOverwrite ClobberPolicy = "overwrite"
+	// ClobberSkip keeps the existing file and writes nothing.
+	ClobberSkip ClobberPolicy = "no-clobber"
+	// ClobberSuffix writes to "name (2).ext", "name (3).ext", ... instead.
+	ClobberSuffix ClobberPolicy = "suffix"
+)
+
+// Clobber is the policy applied by WriteFile.
+var Clobber = ClobberRefuse
+
+// AllowUnsafeDir lets WriteFile write into directories that other users can
+// read or that are synchronised to a cloud service.
+var AllowUnsafeDir bool
+
+func ParseFileMode(s string) (os.FileMode, error) {
+	mode, err := strconv.ParseUint(s, 8, 32)
+	if err != nil || mode > 0777 {
+		return 0, fmt.Errorf("invalid file mode '%s': expected octal permissions such as 0600", s)
+	}
+	return os.FileMode(mode), nil
+}
+
+func dirMode() os.FileMode {
+	mode := FileMode | 0700
+	if FileMode&0040 != 0 {
+		mode |= 0010
+	}
+	if FileMode&0004 != 0 {
+		mode |= 0001
+	}
+	return mode
+}
+
+// WriteFile writes data to filename with FileMode, following Clobber. The
+// data goes to a temporary file in the same directory which is then renamed,
+// so that an interrupted export never leaves a truncated file behind. It
+// returns the name of the file written, or "" when it was skipped.
+func WriteFile(filename string, data []byte) (string, error) {
+	dir := filepath.Dir(filename)
+	if err := checkDirectory(dir); err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(dir, dirMode()); err != nil {
+		return "", fmt.Errorf("failed to create directory '%s': %w", dir, err)
+	}
+
+	tmp, err := writeTemp(dir, data)
+	if err != nil {
+		return "", fmt.Errorf("failed to write to file '%s': %w", filename, err)
+	}
+	defer os.Remove(tmp)
+
+	target := filename
+	for n := 2; ; n++ {
+		err := place(tmp, target)
+		if err == nil {
+			return target, nil
+		}
+		if !errors.Is(err, fs.ErrExist) {
+			return "", fmt.Errorf("failed to write to file '%s': %w", target, err)
+		}
+
+		switch Clobber {
+		case ClobberSkip:
+			logging.Warnf("File '%s' already exists, skipping", target)
+			return "", nil
+		case ClobberSuffix:
+			target = suffixed(filename, n)
+		default:
+			return "", fmt.Errorf("%w: '%s' (use --overwrite, --no-clobber or --suffix)", ErrFileExists, target)
+		}
+	}
+}
+
+// CheckExisting fails with ErrFileExists when any of the files exists and
+// Clobber is ClobberRefuse, so that a batch export is refused before any of
+// its files is written.
+func CheckExisting(filenames []string) error {
+	if Clobber != ClobberRefuse {
+		return nil
+	}
+
+	var existing []string
+	for _, filename := range filenames {
+		if _, err := os.Lstat(filename); err == nil {
+			existing = append(existing, filename)
+		}
+	}
+	if len(existing) > 0 {
+		return fmt.Errorf("%w: '%s' (use --overwrite, --no-clobber or --suffix)", ErrFileExists, strings.Join(existing, "', '"))
+	}
+	return nil
+}
+
+func writeTemp(dir string, data []byte) (string, error) {
+	f, err := os.CreateTemp(dir, ".gauth-*.tmp")
+	if err != nil {
+		return "", err
+	}
+	name := f.Name()
+
+	err = f.Chmod(FileMode)
+	if err == nil {
+		_, err = f.Write(data)
+	}
+	if err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(name)
+		return "", err
+	}
+	return name, nil
+}
+
+// place moves the temporary file to target. Unless overwriting, it links
+// the file so that a file created meanwhile is never replaced, and falls
+// back to a rename on file systems without hard links.
+func place(tmp, target string) error {
+	if Clobber == ClobberOverwrite {
+		return os.Rename(tmp, target)
+	}
+
+	err := os.Link(tmp, target)
+	if err == nil || errors.Is(err, fs.ErrExist) {
+		return err
+	}
+
+	if _, statErr := os.Lstat(target); statErr == nil {
+		return fs.ErrExist
+	}
+	return os.Rename(tmp, target)
+}
+
+func suffixed(filename string, n int) string {
+	ext := filepath.Ext(filename)
+	return fmt.Sprintf("%s (%d)%s", strings.TrimSuffix(filename, ext), n, ext)
+}
+
+// checkDirectory refuses directories where written secrets could end up in
+// other hands: directories other users can list or write to, and folders
+// synchronised by cloud storage clients. Directories that do not exist yet
+// are created private, so only their cloud-synced ancestors matter.
+func checkDirectory(dir string) error {
+	if AllowUnsafeDir {
+		return nil
+	}
+
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return fmt.Errorf("failed to resolve directory '%s': %w", dir, err)
+	}
+	if resolved, err := filepath.EvalSymlinks(abs); err == nil {
+		abs = resolved
+	}
+
+	// Windows permissions are ACLs that the mode bits do not describe.
+	if info, err := os.Stat(abs); err == nil && runtime.GOOS != "windows" && info.Mode().Perm()&0006 != 0 {
+		return fmt.Errorf("%w: '%s' can be read or written by other users (mode %04o); "+
+			"choose a private directory or use --allow-unsafe-dir", ErrUnsafeDirectory, dir, info.Mode().Perm())
+	}
+
+	if service := cloudService(abs); service != "" {
+		return fmt.Errorf("%w: '%s' is synchronised by %s; "+
+			"choose a local directory or use --allow-unsafe-dir", ErrUnsafeDirectory, dir, service)
+	}
+	return nil
+}
+
+// Folder names created by cloud storage clients, in lower case. Folders
+// matched by prefix get a suffix such as "OneDrive - <organisation>" or
+// "Dropbox (Personal)". Names common outside sync clients only match
+// directly under the home directory, where the clients create them.
+var cloudFolders = []struct {
+	name    string
+	prefix  bool
+	home    bool
+	service string
+}{
+	{"dropbox", true, false, "Dropbox"},
+	{"onedrive", true, false, "OneDrive"},
+	{"google drive", true, false, "Google Drive"},
+	{"my drive", false, false, "Google Drive"},
+	{"icloud drive", false, false, "iCloud Drive"},
+	{"mobile documents", false, false, "iCloud Drive"},
+	{"cloudstorage", false, false, "a cloud storage provider"},
+	{"pcloud", true, false, "pCloud"},
+	{"nextcloud", true, false, "Nextcloud"},
+	{"owncloud", true, false, "ownCloud"},
+	{"mega", false, true, "MEGA"},
+	{"megasync", true, false, "MEGA"},
+	{"synologydrive", true, false, "Synology Drive"},
+	{"yandex.disk", true, false, "Yandex Disk"},
+	{"box", false, true, "Box"},
+	{"box sync", false, false, "Box"},
+}
+
+// Files that sync clients keep at the root of the synchronised folder.
+var cloudMarkers = []struct {
+	pattern string
+	service string
+}{
+	{".dropbox", "Dropbox"},
+	{".dropbox.cache", "Dropbox"},
+	{".sync_*.db", "a Nextcloud or ownCloud client"},
+	{"._sync_*.db", "a Nextcloud or ownCloud client"},
+	{".megaignore", "MEGA"},
+}
+
+// cloudService returns the cloud service synchronising dir or one of its
+// ancestors, or "".
+func cloudService(dir string) string {
+	for _, env := range []string{"OneDrive", "OneDriveConsumer", "OneDriveCommercial"} {
+		if root := os.Getenv(env); root != "" && isWithin(dir, root) {
+			return "OneDrive"
+		}
+	}
+
+	home, err := os.UserHomeDir()
+	if err == nil {
+		if resolved, err := filepath.EvalSymlinks(home); err == nil {
+			home = resolved
+		}
+	}
+
+	for p := dir; ; p = filepath.Dir(p) {
+		name := strings.ToLower(filepath.Base(p))
+		for _, folder := range cloudFolders {
+			if folder.home && (home == "" || filepath.Dir(p) != home) {
+				continue
+			}
+			if name == folder.name || (folder.prefix && strings.HasPrefix(name, folder.name)) {
+				return folder.service
+			}
+		}
+
+		for _, marker := range cloudMarkers {
+			if matches, _ := filepath.Glob(filepath.Join(p, marker.pattern)); len(matches) > 0 {
+				return marker.service
+			}
+		}
+
+		if filepath.Dir(p) == p {
+			return ""
+		}
+	}
+}
+
+func isWithin(path, root string) bool {
+	rel, err := filepath.Rel(root, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
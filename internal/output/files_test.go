@@ -0,0 +1,151 @@
+package output
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+// privateDir returns a temporary directory that checkDirectory accepts.
+func privateDir(t *testing.T) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	if err := os.Chmod(dir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func TestWriteFile(t *testing.T) {
+	defer func(c ClobberPolicy) { Clobber = c }(Clobber)
+
+	tests := []struct {
+		policy   ClobberPolicy
+		written  string
+		contents map[string]string
+		err      error
+	}{
+		{ClobberRefuse, "", map[string]string{"out.json": "old"}, ErrFileExists},
+		{ClobberSkip, "", map[string]string{"out.json": "old"}, nil},
+		{ClobberOverwrite, "out.json", map[string]string{"out.json": "new"}, nil},
+		{ClobberSuffix, "out (3).json", map[string]string{"out.json": "old", "out (2).json": "old", "out (3).json": "new"}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.policy), func(t *testing.T) {
+			dir := privateDir(t)
+			for _, name := range []string{"out.json", "out (2).json"} {
+				if err := os.WriteFile(filepath.Join(dir, name), []byte("old"), 0600); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if tt.policy != ClobberSuffix {
+				os.Remove(filepath.Join(dir, "out (2).json"))
+			}
+
+			Clobber = tt.policy
+			written, err := WriteFile(filepath.Join(dir, "out.json"), []byte("new"))
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("Expected error %v, got %v", tt.err, err)
+			}
+			if tt.written != "" {
+				tt.written = filepath.Join(dir, tt.written)
+			}
+			if written != tt.written {
+				t.Errorf("Expected %q to be written, got %q", tt.written, written)
+			}
+
+			entries, _ := os.ReadDir(dir)
+			if len(entries) != len(tt.contents) {
+				t.Errorf("Expected %d files, got %d (temporary file left behind?)", len(tt.contents), len(entries))
+			}
+			for name, expected := range tt.contents {
+				data, err := os.ReadFile(filepath.Join(dir, name))
+				if err != nil || string(data) != expected {
+					t.Errorf("Expected %s to contain %q, got %q (%v)", name, expected, data, err)
+				}
+			}
+		})
+	}
+}
+
+func TestWriteFileModes(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("permission bits are not used on Windows")
+	}
+
+	filename := filepath.Join(privateDir(t), "new", "dir", "accounts.json")
+	if _, err := WriteFile(filename, []byte("{}")); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	for path, expected := range map[string]os.FileMode{
+		filename:                             0600,
+		filepath.Dir(filename):               0700,
+		filepath.Dir(filepath.Dir(filename)): 0700,
+	} {
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Mode().Perm() != expected {
+			t.Errorf("Expected mode %04o for %s, got %04o", expected, path, info.Mode().Perm())
+		}
+	}
+}
+
+func TestCheckDirectory(t *testing.T) {
+	root := t.TempDir()
+	t.Setenv("HOME", root)
+	t.Setenv("USERPROFILE", root)
+	mkdir := func(path string, mode os.FileMode) string {
+		path = filepath.Join(root, path)
+		if err := os.MkdirAll(path, 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chmod(path, mode); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	synced := mkdir("synced", 0700)
+	if err := os.WriteFile(filepath.Join(synced, ".sync_1a2b3c.db"), nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		dir    string
+		unsafe bool
+	}{
+		{"Private directory", mkdir("private", 0700), false},
+		{"Missing directory", filepath.Join(root, "missing", "codes"), false},
+		{"Dropbox", mkdir("Dropbox (Personal)/backup", 0700), true},
+		{"OneDrive for business", mkdir("OneDrive - Example/2fa", 0700), true},
+		{"Not a sync folder", mkdir("megabytes", 0700), false},
+		{"MEGA in the home directory", mkdir("MEGA/keys", 0700), true},
+		{"Folder named like MEGA elsewhere", mkdir("projects/mega", 0700), false},
+		{"Folder named like Box elsewhere", mkdir("projects/box/out", 0700), false},
+		{"Nextcloud client", mkdir("synced/sub", 0700), true},
+	}
+	if runtime.GOOS != "windows" {
+		tests = append(tests, struct {
+			name   string
+			dir    string
+			unsafe bool
+		}{"World-readable", mkdir("shared", 0755), true})
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkDirectory(tt.dir)
+			if unsafe := errors.Is(err, ErrUnsafeDirectory); unsafe != tt.unsafe {
+				t.Errorf("Expected unsafe=%v, got %v", tt.unsafe, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,71 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/Zaphkiel-Ivanovna/GoogleAuthExtractor/internal/decoder"
+)
+
+// freeOTPBackup is a FreeOTP+ JSON backup.
+type freeOTPBackup struct {
+	TokenOrder []string       `json:"tokenOrder"`
+	Tokens     []freeOTPToken `json:"tokens"`
+}
+
+type freeOTPToken struct {
+	Algo      string `json:"algo"`
+	Counter   int64  `json:"counter"`
+	Digits    int    `json:"digits"`
+	IssuerExt string `json:"issuerExt"`
+	IssuerInt string `json:"issuerInt"`
+	Label     string `json:"label"`
+	Period    int    `json:"period"`
+	// Secret holds the raw secret as Java's signed bytes.
+	Secret []int8 `json:"secret"`
+	Type   string `json:"type"`
+}
+
+// encodeFreeOTP returns a FreeOTP+ backup. FreeOTP+ keeps the counter of
+// the next HOTP code, which is the counter Google Authenticator exports.
+func encodeFreeOTP(accounts []decoder.Account) ([]byte, error) {
+	backup := freeOTPBackup{
+		TokenOrder: make([]string, len(accounts)),
+		Tokens:     make([]freeOTPToken, len(accounts)),
+	}
+
+	for i, account := range accounts {
+		secret, err := account.SecretBytes()
+		if err != nil {
+			return nil, err
+		}
+		signed := make([]int8, len(secret))
+		for j, b := range secret {
+			signed[j] = int8(b)
+		}
+
+		backup.Tokens[i] = freeOTPToken{
+			Algo:      account.AlgorithmOrDefault(),
+			Counter:   account.Counter,
+			Digits:    account.DigitCount(),
+			IssuerExt: account.Issuer,
+			IssuerInt: account.Issuer,
+			Label:     account.Name,
+			Period:    period(),
+			Secret:    signed,
+			Type:      otpType(account),
+		}
+
+		// FreeOTP+ identifies tokens by "issuer:label" to keep their order.
+		backup.TokenOrder[i] = account.Name
+		if account.Issuer != "" {
+			backup.TokenOrder[i] = account.Issuer + ":" + account.Name
+		}
+	}
+
+	data, err := json.MarshalIndent(backup, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal account data: %w", err)
+	}
+	return data, nil
+}
@@ -2,48 +2,39 @@ package output
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
-	"os"
-	"path/filepath"
 
 	"github.com/Zaphkiel-Ivanovna/GoogleAuthExtractor/internal/decoder"
-	"github.com/fatih/color"
+	"github.com/Zaphkiel-Ivanovna/GoogleAuthExtractor/internal/logging"
 )
 
-func SaveToJSON(accounts []decoder.Account, filename string) error {
-
-	if _, err := os.Stat(filename); err == nil {
-		return fmt.Errorf("file '%s' already exists", filename)
-	}
+var ErrFileExists = errors.New("file already exists")
 
-	dir := filepath.Dir(filename)
-	if dir != "." {
-		if err := os.MkdirAll(dir, 0755); err != nil {
-			return fmt.Errorf("failed to create directory '%s': %w", dir, err)
-		}
-	}
+func SaveToJSON(accounts []decoder.Account, filename string) error {
 
 	data, err := json.MarshalIndent(accounts, "", "  ")
 	if err != nil {
 		return fmt.Errorf("failed to marshal account data: %w", err)
 	}
 
-	if err := os.WriteFile(filename, data, 0644); err != nil {
-		return fmt.Errorf("failed to write to file '%s': %w", filename, err)
+	written, err := WriteFile(filename, data)
+	if err != nil || written == "" {
+		return err
 	}
 
-	color.Green("Successfully saved %d accounts to %s", len(accounts), filename)
+	logging.Successf("Successfully saved %d accounts to %s", len(accounts), written)
 	return nil
 }
 
-func PrintJSON(accounts []decoder.Account) {
+func PrintJSON(accounts []decoder.Account) error {
 
 	data, err := json.MarshalIndent(accounts, "", "  ")
 	if err != nil {
-		color.Red("Error: Failed to marshal account data: %v", err)
-		return
+		return fmt.Errorf("failed to marshal account data: %w", err)
 	}
 
 	fmt.Println(string(data))
-	color.Yellow("Note: When adding accounts to other authenticator apps, use the 'totpSecret' value as the secret key, not the 'secret' value.")
+	logging.Infof("Note: When adding accounts to other authenticator apps, use the 'totpSecret' value as the secret key, not the 'secret' value.")
+	return nil
 }
@@ -0,0 +1,201 @@
+package output
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/Zaphkiel-Ivanovna/GoogleAuthExtractor/internal/decoder"
+	"github.com/Zaphkiel-Ivanovna/GoogleAuthExtractor/internal/logging"
+	"golang.org/x/term"
+)
+
+var ErrNotTerminal = errors.New("paged QR codes need an interactive terminal")
+
+const (
+	enterAltScreen = "\x1b[?1049h\x1b[?25l"
+	leaveAltScreen = "\x1b[?25h\x1b[?1049l"
+	clearScreen    = "\x1b[H\x1b[2J"
+	// clearScrollback also erases the lines scrolled off the screen, where
+	// secrets printed before the pager started could still be read.
+	clearScrollback = "\x1b[H\x1b[2J\x1b[3J"
+	// deleteKittyImages removes images that kitty keeps over cleared text.
+	deleteKittyImages = "\x1b_Ga=d\x1b\\"
+)
+
+type pageAction int
+
+const (
+	pageNone pageAction = iota
+	pageNext
+	pagePrevious
+	pageSkip
+	pageQuit
+)
+
+// pageStatus records what was done with an account in the pager.
+type pageStatus int
+
+const (
+	pageUnseen pageStatus = iota
+	pageDone
+	pageSkipped
+)
+
+// pageKey maps one read from the raw terminal to an action. Arrow keys
+// arrive as escape sequences, a lone escape is the Esc key.
+func pageKey(key []byte) pageAction {
+	switch string(key) {
+	case "n", " ", "\r", "\n", "j", "\x1b[C", "\x1b[B", "\x1b[6~":
+		return pageNext
+	case "p", "b", "k", "\x7f", "\x1b[D", "\x1b[A", "\x1b[5~":
+		return pagePrevious
+	case "s":
+		return pageSkip
+	case "q", "Q", "\x1b", "\x03", "\x04":
+		return pageQuit
+	}
+	return pageNone
+}
+
+// PageQRCodes shows the QR code of one account at a time on the alternate
+// screen and waits for a key before the next one, so that only one secret is
+// visible at once. The screen and scrollback are wiped on exit.
+func PageQRCodes(accounts []decoder.Account, opts TerminalOptions) error {
+	stdin, stdout := int(os.Stdin.Fd()), int(os.Stdout.Fd())
+	if !term.IsTerminal(stdin) || !term.IsTerminal(stdout) {
+		return ErrNotTerminal
+	}
+
+	status, err := pageOnAltScreen(stdin, accounts, opts)
+	if err != nil {
+		return err
+	}
+	reportPages(accounts, status)
+	return nil
+}
+
+// pageOnAltScreen runs the pager with the terminal in raw mode, and wipes
+// the screen and restores the terminal however the pager ends.
+func pageOnAltScreen(stdin int, accounts []decoder.Account, opts TerminalOptions) ([]pageStatus, error) {
+	state, err := term.MakeRaw(stdin)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read keys from the terminal: %w", err)
+	}
+	defer term.Restore(stdin, state)
+
+	cols, rows, _ := terminalSize()
+	mode := opts.resolve(true)
+
+	fmt.Fprint(os.Stdout, enterAltScreen)
+	defer func() {
+		if mode == TerminalKitty {
+			fmt.Fprint(os.Stdout, deleteKittyImages)
+		}
+		fmt.Fprint(os.Stdout, clearScreen+leaveAltScreen+clearScrollback)
+	}()
+
+	return runPager(os.Stdin, os.Stdout, accounts, opts, mode, cols, rows)
+}
+
+// runPager draws the pages on out and follows the keys read from in until
+// the last account is passed or the user quits.
+func runPager(in io.Reader, out io.Writer, accounts []decoder.Account, opts TerminalOptions,
+	mode TerminalMode, cols, rows int) ([]pageStatus, error) {
+	opts.Mode = mode
+	status := make([]pageStatus, len(accounts))
+
+	key := make([]byte, 8)
+	for i := 0; i < len(accounts); {
+		page, err := renderPage(accounts, i, status, opts, cols, rows)
+		if err != nil {
+			return status, err
+		}
+		if _, err := io.WriteString(out, page); err != nil {
+			return status, err
+		}
+
+		n, err := in.Read(key)
+		if err == io.EOF {
+			return status, nil
+		}
+		if err != nil {
+			return status, fmt.Errorf("failed to read key: %w", err)
+		}
+
+		switch pageKey(key[:n]) {
+		case pageNext:
+			status[i] = pageDone
+			i++
+		case pageSkip:
+			status[i] = pageSkipped
+			i++
+		case pagePrevious:
+			if i > 0 {
+				i--
+			}
+		case pageQuit:
+			return status, nil
+		}
+	}
+	return status, nil
+}
+
+func renderPage(accounts []decoder.Account, i int, status []pageStatus, opts TerminalOptions, cols, rows int) (string, error) {
+	account := accounts[i]
+	uri := generateOtpAuthURI(account)
+
+	qr, warning, err := renderTerminalQR(uri, displayName(account), opts, cols, rows-terminalReservedLines, true)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate QR code for account '%s': %w", account.Name, err)
+	}
+
+	var b strings.Builder
+	if opts.Mode == TerminalKitty {
+		b.WriteString(deleteKittyImages)
+	}
+	b.WriteString(clearScreen)
+
+	mark := ""
+	switch status[i] {
+	case pageDone:
+		mark = " (done)"
+	case pageSkipped:
+		mark = " (skipped)"
+	}
+	fmt.Fprintf(&b, "\x1b[1;36m[%d/%d] %s%s\x1b[0m\n", i+1, len(accounts), displayName(account), mark)
+	b.WriteString(qr)
+	if warning != "" {
+		fmt.Fprintf(&b, "\x1b[33m%s\x1b[0m\n", warning)
+	}
+	b.WriteString("n/space: next   p: previous   s: skip   q: quit")
+
+	// The terminal is in raw mode, where a line feed does not return the
+	// cursor to the first column.
+	return strings.ReplaceAll(b.String(), "\n", "\r\n"), nil
+}
+
+func reportPages(accounts []decoder.Account, status []pageStatus) {
+	done := 0
+	var skipped, unseen []string
+	for i, s := range status {
+		switch s {
+		case pageDone:
+			done++
+		case pageSkipped:
+			skipped = append(skipped, displayName(accounts[i]))
+		default:
+			unseen = append(unseen, displayName(accounts[i]))
+		}
+	}
+
+	logging.Infof("Went through %d of %d QR codes", done, len(accounts))
+	if len(skipped) > 0 {
+		logging.Warnf("Skipped: %s", strings.Join(skipped, ", "))
+	}
+	if len(unseen) > 0 {
+		logging.Warnf("Not shown: %s", strings.Join(unseen, ", "))
+	}
+}
@@ -0,0 +1,64 @@
+package output
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Zaphkiel-Ivanovna/GoogleAuthExtractor/internal/decoder"
+)
+
+// keyReader returns one scripted key per read, like a raw terminal.
+type keyReader struct {
+	keys []string
+}
+
+func (r *keyReader) Read(p []byte) (int, error) {
+	if len(r.keys) == 0 {
+		return 0, io.EOF
+	}
+	n := copy(p, r.keys[0])
+	r.keys = r.keys[1:]
+	return n, nil
+}
+
+func TestRunPager(t *testing.T) {
+	accounts := []decoder.Account{
+		{Name: "alice", Issuer: "GitHub", Secret: "SGVsbG8h", Type: "TOTP"},
+		{Name: "bob", Secret: "SGVsbG8h", Type: "TOTP"},
+		{Name: "carol", Secret: "SGVsbG8h", Type: "TOTP"},
+	}
+
+	tests := []struct {
+		name     string
+		keys     []string
+		expected []pageStatus
+		pages    int
+	}{
+		{"Next through all", []string{"n", " ", "\r"}, []pageStatus{pageDone, pageDone, pageDone}, 3},
+		{"Skip and go back", []string{"s", "\x1b[D", "n", "x", "n", "s"}, []pageStatus{pageDone, pageDone, pageSkipped}, 6},
+		{"Quit early", []string{"n", "q"}, []pageStatus{pageDone, pageUnseen, pageUnseen}, 2},
+		{"Previous on first page", []string{"p", "\x03"}, []pageStatus{pageUnseen, pageUnseen, pageUnseen}, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			status, err := runPager(&keyReader{keys: tt.keys}, &out, accounts, TerminalOptions{}, TerminalHalfBlock, 200, 100)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(status, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, status)
+			}
+			if pages := strings.Count(out.String(), clearScreen); pages != tt.pages {
+				t.Errorf("Expected %d pages drawn, got %d", tt.pages, pages)
+			}
+			if strings.Contains(strings.ReplaceAll(out.String(), "\r\n", ""), "\n") {
+				t.Errorf("Expected every line feed to be preceded by a carriage return")
+			}
+		})
+	}
+}
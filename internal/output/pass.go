@@ -0,0 +1,270 @@
+package output
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/Zaphkiel-Ivanovna/GoogleAuthExtractor/internal/decoder"
+	"github.com/Zaphkiel-Ivanovna/GoogleAuthExtractor/internal/logging"
+)
+
+var ErrNoRecipients = errors.New("no recipients for the password store")
+
+// PassOptions configures the export to a pass password store.
+type PassOptions struct {
+	// Store is the root of the password store.
+	Store string
+	// Keyrings are files of OpenPGP public keys, armored or binary, in which
+	// the recipients listed in .gpg-id are looked up.
+	Keyrings []string
+}
+
+// PassStoreDir returns the password store used by pass: $PASSWORD_STORE_DIR,
+// or ~/.password-store.
+func PassStoreDir() string {
+	if dir := os.Getenv("PASSWORD_STORE_DIR"); dir != "" {
+		return dir
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ".password-store"
+	}
+	return filepath.Join(home, ".password-store")
+}
+
+// DefaultKeyrings returns the GnuPG public keyring when it is in the legacy
+// OpenPGP format. Newer GnuPG versions keep keys in a keybox that cannot be
+// read without gpg; export the keys to a file for --keyring instead.
+func DefaultKeyrings() []string {
+	home := os.Getenv("GNUPGHOME")
+	if home == "" {
+		userHome, err := os.UserHomeDir()
+		if err != nil {
+			return nil
+		}
+		home = filepath.Join(userHome, ".gnupg")
+	}
+
+	keyring := filepath.Join(home, "pubring.gpg")
+	if info, err := os.Stat(keyring); err == nil && info.Size() > 0 {
+		return []string{keyring}
+	}
+	return nil
+}
+
+// SaveToPass writes one pass-otp entry per account to otp/<issuer>/<name>.gpg
+// in the password store, each holding the account's otpauth URI encrypted to
+// the recipients of the nearest .gpg-id file, as pass does.
+func SaveToPass(accounts []decoder.Account, opts PassOptions) error {
+	keyring, err := loadKeyrings(opts.Keyrings)
+	if err != nil {
+		return err
+	}
+
+	filenames := passEntryNames(accounts, opts.Store)
+	if err := CheckExisting(filenames); err != nil {
+		return err
+	}
+
+	recipients := make(map[string]openpgp.EntityList)
+	for i, account := range accounts {
+		ids, err := readGPGID(opts.Store, filepath.Dir(filenames[i]))
+		if err != nil {
+			return err
+		}
+
+		key := strings.Join(ids, "\n")
+		if _, ok := recipients[key]; !ok {
+			if recipients[key], err = findRecipients(keyring, ids); err != nil {
+				return err
+			}
+		}
+
+		data, err := encryptEntry(generateOtpAuthURI(account)+"\n", recipients[key])
+		if err != nil {
+			return fmt.Errorf("failed to encrypt pass entry for '%s': %w", account.Name, err)
+		}
+
+		written, err := WriteFile(filenames[i], data)
+		if err != nil {
+			return err
+		}
+		if written != "" {
+			logging.Successf("Created pass entry: %s", passEntryName(opts.Store, written))
+		}
+	}
+
+	return nil
+}
+
+// passEntryNames returns otp/<issuer>/<name>.gpg in store for each account,
+// numbering names that would collide.
+func passEntryNames(accounts []decoder.Account, store string) []string {
+	names := make([]string, len(accounts))
+	used := make(map[string]bool)
+
+	for i, account := range accounts {
+		base := filepath.Join("otp", passComponent(account.Name))
+		if account.Issuer != "" {
+			base = filepath.Join("otp", passComponent(account.Issuer), passComponent(account.Name))
+		}
+
+		name := base + ".gpg"
+		for n := 2; used[strings.ToLower(name)]; n++ {
+			name = fmt.Sprintf("%s (%d).gpg", base, n)
+		}
+		used[strings.ToLower(name)] = true
+		names[i] = filepath.Join(store, name)
+	}
+	return names
+}
+
+// passComponent makes s safe as one element of a pass entry name. Unlike
+// sanitizeFilename it keeps characters such as "@" and "+", since pass users
+// look entries up by the account's label.
+func passComponent(s string) string {
+	s = strings.Map(func(r rune) rune {
+		if r == '/' || r == '\\' || unicode.IsControl(r) || unicode.Is(unicode.Cf, r) {
+			return '_'
+		}
+		return r
+	}, strings.ToValidUTF8(s, "_"))
+
+	s = truncateFilename(s)
+	if s == "" || s == "." || s == ".." {
+		return "_"
+	}
+	return s
+}
+
+// passEntryName returns the name pass shows for the entry in filename.
+func passEntryName(store, filename string) string {
+	if rel, err := filepath.Rel(store, filename); err == nil {
+		filename = rel
+	}
+	return filepath.ToSlash(strings.TrimSuffix(filename, ".gpg"))
+}
+
+// readGPGID returns the recipients in the .gpg-id file closest to dir
+// within store, skipping blank lines and comments.
+func readGPGID(store, dir string) ([]string, error) {
+	for {
+		data, err := os.ReadFile(filepath.Join(dir, ".gpg-id"))
+		if err == nil {
+			var ids []string
+			scanner := bufio.NewScanner(bytes.NewReader(data))
+			for scanner.Scan() {
+				line, _, _ := strings.Cut(scanner.Text(), "#")
+				if line = strings.TrimSpace(line); line != "" {
+					ids = append(ids, line)
+				}
+			}
+			if len(ids) == 0 {
+				return nil, fmt.Errorf("%w: '%s' is empty", ErrNoRecipients, filepath.Join(dir, ".gpg-id"))
+			}
+			return ids, nil
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("failed to read .gpg-id: %w", err)
+		}
+
+		if !isWithin(dir, store) || filepath.Clean(dir) == filepath.Clean(store) {
+			return nil, fmt.Errorf("%w: no .gpg-id file in '%s' (run 'pass init' first)", ErrNoRecipients, store)
+		}
+		dir = filepath.Dir(dir)
+	}
+}
+
+func loadKeyrings(paths []string) (openpgp.EntityList, error) {
+	var keyring openpgp.EntityList
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read keyring: %w", err)
+		}
+
+		var entities openpgp.EntityList
+		if bytes.Contains(data, []byte("-----BEGIN PGP")) {
+			entities, err = openpgp.ReadArmoredKeyRing(bytes.NewReader(data))
+		} else {
+			entities, err = openpgp.ReadKeyRing(bytes.NewReader(data))
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read keyring '%s': %w", path, err)
+		}
+		keyring = append(keyring, entities...)
+	}
+	return keyring, nil
+}
+
+// findRecipients returns the keys for the .gpg-id entries, which are key
+// IDs or fingerprints in hex, or email addresses or names of user IDs.
+func findRecipients(keyring openpgp.EntityList, ids []string) (openpgp.EntityList, error) {
+	var recipients openpgp.EntityList
+	for _, id := range ids {
+		entity := findKey(keyring, id)
+		if entity == nil {
+			return nil, fmt.Errorf("%w: no public key for '%s' in the keyring (export it with 'gpg --export %s > key.gpg' and pass --keyring key.gpg)", ErrNoRecipients, id, id)
+		}
+		recipients = append(recipients, entity)
+	}
+	return recipients, nil
+}
+
+func findKey(keyring openpgp.EntityList, id string) *openpgp.Entity {
+	hexID := strings.ToUpper(strings.TrimPrefix(strings.ReplaceAll(id, " ", ""), "0x"))
+	if _, err := hex.DecodeString(hexID); err == nil && (len(hexID) == 8 || len(hexID) == 16 || len(hexID) == 40 || len(hexID) == 64) {
+		for _, entity := range keyring {
+			if strings.HasSuffix(strings.ToUpper(hex.EncodeToString(entity.PrimaryKey.Fingerprint)), hexID) {
+				return entity
+			}
+			for _, subkey := range entity.Subkeys {
+				if strings.HasSuffix(strings.ToUpper(hex.EncodeToString(subkey.PublicKey.Fingerprint)), hexID) {
+					return entity
+				}
+			}
+		}
+		return nil
+	}
+
+	email := strings.TrimSuffix(strings.TrimPrefix(id, "<"), ">")
+	for _, entity := range keyring {
+		for _, identity := range entity.Identities {
+			if strings.EqualFold(identity.UserId.Email, email) {
+				return entity
+			}
+		}
+	}
+	// Like gpg, fall back to a case-insensitive substring of the user ID.
+	for _, entity := range keyring {
+		for name := range entity.Identities {
+			if strings.Contains(strings.ToLower(name), strings.ToLower(id)) {
+				return entity
+			}
+		}
+	}
+	return nil
+}
+
+func encryptEntry(content string, recipients openpgp.EntityList) ([]byte, error) {
+	var b bytes.Buffer
+	w, err := openpgp.Encrypt(&b, recipients, nil, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write([]byte(content)); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
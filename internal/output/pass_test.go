@@ -0,0 +1,125 @@
+package output
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/Zaphkiel-Ivanovna/GoogleAuthExtractor/internal/decoder"
+)
+
+func newTestKey(t *testing.T, name, email string) *openpgp.Entity {
+	t.Helper()
+
+	entity, err := openpgp.NewEntity(name, "", email, &packet.Config{Algorithm: packet.PubKeyAlgoEdDSA})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return entity
+}
+
+func TestSaveToPass(t *testing.T) {
+	alice := newTestKey(t, "Alice", "alice@example.com")
+	bob := newTestKey(t, "Bob", "bob@example.com")
+
+	root := privateDir(t)
+	store := filepath.Join(root, "store")
+	keyring := filepath.Join(root, "keys.gpg")
+
+	var keys bytes.Buffer
+	for _, entity := range []*openpgp.Entity{alice, bob} {
+		if err := entity.Serialize(&keys); err != nil {
+			t.Fatal(err)
+		}
+	}
+	files := map[string]string{
+		keyring:                         keys.String(),
+		filepath.Join(store, ".gpg-id"): "alice@example.com # personal key\n",
+		filepath.Join(store, "otp", "Work", ".gpg-id"): "Bob\n",
+	}
+	for path, content := range files {
+		if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	accounts := []decoder.Account{
+		{Name: "alice@example.com", Issuer: "Example", TOTPSecret: "JBSWY3DPEHPK3PXP", Type: "TOTP", Digits: "EIGHT"},
+		{Name: "build", Issuer: "Work", TOTPSecret: "GEZDGNBVGY3TQOJQ", Type: "HOTP", Counter: 3},
+	}
+	if err := SaveToPass(accounts, PassOptions{Store: store, Keyrings: []string{keyring}}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		entry    string
+		key      *openpgp.Entity
+		expected string
+	}{
+		{"otp/Example/alice@example.com.gpg", alice, "otpauth://totp/alice@example.com?secret=JBSWY3DPEHPK3PXP&issuer=Example&digits=8\n"},
+		{"otp/Work/build.gpg", bob, "otpauth://hotp/build?secret=GEZDGNBVGY3TQOJQ&issuer=Work&counter=3\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.entry, func(t *testing.T) {
+			data, err := os.ReadFile(filepath.Join(store, filepath.FromSlash(tt.entry)))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			md, err := openpgp.ReadMessage(bytes.NewReader(data), openpgp.EntityList{tt.key}, nil, nil)
+			if err != nil {
+				t.Fatalf("Failed to decrypt entry: %v", err)
+			}
+			content, err := io.ReadAll(md.UnverifiedBody)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(content) != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, content)
+			}
+		})
+	}
+}
+
+func TestSaveToPassUnknownRecipient(t *testing.T) {
+	store := privateDir(t)
+	if err := os.WriteFile(filepath.Join(store, ".gpg-id"), []byte("0xDEADBEEF\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	err := SaveToPass([]decoder.Account{testAccount}, PassOptions{Store: store})
+	if !errors.Is(err, ErrNoRecipients) {
+		t.Errorf("Expected ErrNoRecipients, got %v", err)
+	}
+}
+
+func TestPassEntryNames(t *testing.T) {
+	accounts := []decoder.Account{
+		{Name: "alice+otp@example.com", Issuer: "Example"},
+		{Name: "../evil", Issuer: ".."},
+		{Name: "line\nbreak", Issuer: "a/b"},
+		{Name: "Alice+OTP@example.com", Issuer: "example"},
+	}
+	expected := []string{
+		"otp/Example/alice+otp@example.com.gpg",
+		"otp/_/.._evil.gpg",
+		"otp/a_b/line_break.gpg",
+		"otp/example/Alice+OTP@example.com (2).gpg",
+	}
+
+	names := passEntryNames(accounts, "store")
+	for i, name := range expected {
+		if want := filepath.Join("store", filepath.FromSlash(name)); names[i] != want {
+			t.Errorf("Expected %q, got %q", want, names[i])
+		}
+	}
+}
@@ -6,10 +6,27 @@ import (
 	"time"
 
 	"github.com/Zaphkiel-Ivanovna/GoogleAuthExtractor/internal/decoder"
+	"github.com/Zaphkiel-Ivanovna/GoogleAuthExtractor/internal/logging"
 	"github.com/fatih/color"
 )
 
-func PrettyPrintAccounts(accounts []decoder.Account, pretty bool, showFullSecrets bool) {
+type Masking string
+
+const (
+	MaskPartial Masking = "partial"
+	MaskFull    Masking = "full"
+	MaskNone    Masking = "none"
+)
+
+func ParseMasking(s string) (Masking, error) {
+	switch m := Masking(s); m {
+	case MaskPartial, MaskFull, MaskNone:
+		return m, nil
+	}
+	return "", fmt.Errorf("invalid masking policy '%s': expected partial, full or none", s)
+}
+
+func PrettyPrintAccounts(accounts []decoder.Account, pretty bool, masking Masking) {
 	if !pretty {
 
 		fmt.Println("+-----------------------+-----------------------+----------+----------+")
@@ -57,20 +74,17 @@ func PrettyPrintAccounts(accounts []decoder.Account, pretty bool, showFullSecret
 			fmt.Printf("  %s: %d\n", cyan("Counter"), account.Counter)
 		}
 
-		if showFullSecrets {
-			fmt.Printf("  %s: %s\n", cyan("Secret (BASE32)"), account.TOTPSecret)
-			fmt.Printf("  %s: %s\n", cyan("Secret (BASE64)"), account.Secret)
+		if masking == MaskNone {
+			fmt.Printf("  %s: %s\n", cyan("Secret (BASE32)"), account.Base32())
+			fmt.Printf("  %s: %s\n", cyan("Secret (BASE64)"), account.Base64())
 
 			fmt.Printf("\n  %s\n", red("⚠️  Warning: Full secrets are displayed. Clear your terminal history when done."))
 		} else {
 
-			secretLen := len(account.TOTPSecret)
-			if secretLen > 4 {
-				visiblePart := account.TOTPSecret[0:4]
-				hiddenPart := strings.Repeat("*", secretLen-4)
-				fmt.Printf("  %s: %s%s\n", cyan("Secret"), visiblePart, hiddenPart)
+			if masking == MaskFull || account.Fingerprint == "" {
+				fmt.Printf("  %s: %s\n", cyan("Secret"), "********")
 			} else {
-				fmt.Printf("  %s: %s\n", cyan("Secret"), "****")
+				fmt.Printf("  %s: %s\n", cyan("Fingerprint"), account.Fingerprint)
 			}
 
 			fmt.Printf("\n  To view full secret: %s\n", yellow("Use 'gauth-extractor view -s' or 'gauth-extractor json -s=false'"))
@@ -78,7 +92,7 @@ func PrettyPrintAccounts(accounts []decoder.Account, pretty bool, showFullSecret
 	}
 
 	fmt.Println()
-	color.Yellow("Note: When adding accounts to other authenticator apps, use the 'totpSecret' value as the secret key, not the 'secret' value.")
+	logging.Infof("Note: When adding accounts to other authenticator apps, use the 'totpSecret' value as the secret key, not the 'secret' value.")
 }
 
 func truncateString(s string, maxLen int) string {
@@ -4,41 +4,56 @@ import (
 	"fmt"
 	"net/url"
 	"os"
-	"path/filepath"
-	"regexp"
 	"strings"
 
 	"github.com/Zaphkiel-Ivanovna/GoogleAuthExtractor/internal/decoder"
+	"github.com/Zaphkiel-Ivanovna/GoogleAuthExtractor/internal/logging"
 	"github.com/fatih/color"
-	"github.com/skip2/go-qrcode"
 )
 
-func SaveToQRCodes(accounts []decoder.Account, directory string) error {
+func SaveToQRCodes(accounts []decoder.Account, directory string, opts QRImageOptions) error {
 
-	if err := os.MkdirAll(directory, 0755); err != nil {
-		return fmt.Errorf("failed to create directory '%s': %w", directory, err)
+	filenames, err := accountFileNames(accounts, directory, "."+string(opts.Format))
+	if err != nil {
+		return err
+	}
+	if err := CheckExisting(filenames); err != nil {
+		return err
 	}
 
-	for _, account := range accounts {
-
-		uri := generateOtpAuthURI(account)
-
-		filename := generateFilename(account, directory)
+	for i, account := range accounts {
 
-		if _, err := os.Stat(filename); err == nil {
-			color.Yellow("Warning: File '%s' already exists, skipping", filename)
+		data, err := QRImage(account, opts)
+		if err != nil {
+			logging.Errorf("Failed to create QR code for '%s': %v", account.Name, err)
 			continue
 		}
 
-		err := qrcode.WriteFile(uri, qrcode.Medium, 256, filename)
+		written, err := WriteFile(filenames[i], data)
 		if err != nil {
-			color.Red("Error: Failed to create QR code for '%s': %v", account.Name, err)
-			continue
+			return err
 		}
+		if written != "" {
+			logging.Successf("Created QR code: %s", written)
+		}
+	}
 
-		color.Green("Created QR code: %s", filename)
+	return nil
+}
+
+func SaveContactSheet(accounts []decoder.Account, filename string, opts QRImageOptions, columns int) error {
+
+	data, err := ContactSheet(accounts, opts, columns)
+	if err != nil {
+		return err
 	}
 
+	written, err := WriteFile(filename, data)
+	if err != nil || written == "" {
+		return err
+	}
+
+	logging.Successf("Created contact sheet of %d QR codes: %s", len(accounts), written)
 	return nil
 }
 
@@ -49,7 +64,7 @@ func generateOtpAuthURI(account decoder.Account) string {
 	}
 
 	label := url.PathEscape(account.Name)
-	secret := url.QueryEscape(account.TOTPSecret)
+	secret := url.QueryEscape(account.Base32())
 	issuer := url.QueryEscape(account.Issuer)
 
 	uri := fmt.Sprintf("otpauth://%s/%s?secret=%s", otpType, label, secret)
@@ -58,6 +73,14 @@ func generateOtpAuthURI(account decoder.Account) string {
 		uri += fmt.Sprintf("&issuer=%s", issuer)
 	}
 
+	if algorithm := account.AlgorithmOrDefault(); algorithm != "SHA1" {
+		uri += fmt.Sprintf("&algorithm=%s", algorithm)
+	}
+
+	if digits := account.DigitCount(); digits != 6 {
+		uri += fmt.Sprintf("&digits=%d", digits)
+	}
+
 	if account.Type == "HOTP" {
 		uri += fmt.Sprintf("&counter=%d", account.Counter)
 	}
@@ -65,49 +88,26 @@ func generateOtpAuthURI(account decoder.Account) string {
 	return uri
 }
 
-func generateFilename(account decoder.Account, directory string) string {
-	issuer := account.Issuer
-	if issuer == "" {
-		issuer = "No_Issuer"
+func DisplayQRCodesInTerminal(accounts []decoder.Account, opts TerminalOptions) error {
+	if opts.Paged {
+		return PageQRCodes(accounts, opts)
 	}
 
-	name := sanitizeFilename(account.Name)
-	issuer = sanitizeFilename(issuer)
-
-	return filepath.Join(directory, fmt.Sprintf("%s (%s).png", issuer, name))
-}
-
-func sanitizeFilename(name string) string {
-
-	re := regexp.MustCompile(`[\\/:*?"<>|%&{}$+!'=@]`)
-	return re.ReplaceAllString(name, "_")
-}
-
-func DisplayQRCodesInTerminal(accounts []decoder.Account) error {
 	for i, account := range accounts {
 		uri := generateOtpAuthURI(account)
 
-		qr, err := qrcode.New(uri, qrcode.Medium)
-		if err != nil {
-			return fmt.Errorf("failed to generate QR code for account '%s': %w", account.Name, err)
-		}
-
-		asciiQR := qr.ToString(false)
-
-		displayName := account.Name
-		if account.Issuer != "" {
-			displayName = fmt.Sprintf("%s (%s)", account.Issuer, account.Name)
-		}
-
 		if i > 0 {
 			fmt.Println("\n" + strings.Repeat("-", 80) + "\n")
 		}
 
 		color.Set(color.FgCyan, color.Bold)
-		fmt.Printf("QR Code for: %s\n\n", displayName)
+		fmt.Printf("QR Code for: %s\n\n", displayName(account))
 		color.Unset()
 
-		fmt.Println(asciiQR)
+		if err := writeTerminalQR(os.Stdout, uri, displayName(account), opts); err != nil {
+			return fmt.Errorf("failed to generate QR code for account '%s': %w", account.Name, err)
+		}
+
 		fmt.Printf("\nOTP Type: %s\n", account.Type)
 		fmt.Printf("URI: %s\n", uri)
 	}
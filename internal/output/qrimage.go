@@ -0,0 +1,360 @@
+package output
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/Zaphkiel-Ivanovna/GoogleAuthExtractor/internal/decoder"
+	"github.com/skip2/go-qrcode"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+)
+
+type ImageFormat string
+
+const (
+	ImagePNG ImageFormat = "png"
+	ImageSVG ImageFormat = "svg"
+)
+
+func ParseImageFormat(s string) (ImageFormat, error) {
+	switch f := ImageFormat(strings.ToLower(s)); f {
+	case ImagePNG, ImageSVG:
+		return f, nil
+	}
+	return "", fmt.Errorf("invalid image format '%s': expected png or svg", s)
+}
+
+// ParseRecoveryLevel accepts the usual one-letter error correction levels
+// and their names.
+func ParseRecoveryLevel(s string) (qrcode.RecoveryLevel, error) {
+	switch strings.ToLower(s) {
+	case "l", "low":
+		return qrcode.Low, nil
+	case "m", "medium":
+		return qrcode.Medium, nil
+	case "q", "quartile":
+		return qrcode.High, nil
+	case "h", "high", "highest":
+		return qrcode.Highest, nil
+	}
+	return 0, fmt.Errorf("invalid error correction level '%s': expected L, M, Q or H", s)
+}
+
+// ParseColor reads a #rgb, #rgba, #rrggbb or #rrggbbaa hex colour.
+func ParseColor(s string) (color.NRGBA, error) {
+	hex := strings.TrimPrefix(s, "#")
+	if len(hex) == 3 || len(hex) == 4 {
+		var long []byte
+		for i := 0; i < len(hex); i++ {
+			long = append(long, hex[i], hex[i])
+		}
+		hex = string(long)
+	}
+	if len(hex) == 6 {
+		hex += "ff"
+	}
+
+	v, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil || len(hex) != 8 {
+		return color.NRGBA{}, fmt.Errorf("invalid colour '%s': expected #rgb, #rgba, #rrggbb or #rrggbbaa", s)
+	}
+	return color.NRGBA{R: uint8(v >> 24), G: uint8(v >> 16), B: uint8(v >> 8), A: uint8(v)}, nil
+}
+
+// MaxQRImageSize is the largest code size accepted, in pixels, which is
+// plenty for printing.
+const MaxQRImageSize = 4096
+
+// maxSheetPixels bounds contact sheets, which hold every code at full size.
+const maxSheetPixels = 40_000_000
+
+type QRImageOptions struct {
+	Format ImageFormat
+	// Size is the width of the code in pixels, quiet zone included.
+	Size int
+	// QuietZone is the light border around the code, in modules.
+	QuietZone  int
+	Level      qrcode.RecoveryLevel
+	Foreground color.NRGBA
+	Background color.NRGBA
+	// Caption writes the issuer and name of the account under the code.
+	Caption bool
+}
+
+func DefaultQRImageOptions() QRImageOptions {
+	return QRImageOptions{
+		Format:     ImagePNG,
+		Size:       256,
+		QuietZone:  4,
+		Level:      qrcode.Medium,
+		Foreground: color.NRGBA{A: 0xff},
+		Background: color.NRGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff},
+	}
+}
+
+// qrTile is one account's code laid out in pixels: the modules are drawn
+// modulePx wide from (offset, offset), followed by the caption lines.
+type qrTile struct {
+	bits     [][]bool
+	modulePx int
+	offset   int
+	width    int
+	height   int
+	lines    []string
+	fontSize int
+}
+
+func layoutTile(account decoder.Account, opts QRImageOptions, caption bool) (qrTile, error) {
+	if opts.Size > MaxQRImageSize {
+		return qrTile{}, fmt.Errorf("a size of %d pixels is too large: at most %d are supported", opts.Size, MaxQRImageSize)
+	}
+	if opts.QuietZone < 0 || opts.QuietZone > opts.Size/2 {
+		return qrTile{}, fmt.Errorf("a quiet zone of %d modules does not fit in %d pixels", opts.QuietZone, opts.Size)
+	}
+	bits, err := qrBitmap(generateOtpAuthURI(account), opts.Level, opts.QuietZone)
+	if err != nil {
+		return qrTile{}, err
+	}
+	if opts.Size < len(bits) {
+		return qrTile{}, fmt.Errorf("a size of %d pixels is too small for this code: at least %d are needed", opts.Size, len(bits))
+	}
+
+	tile := qrTile{
+		bits:     bits,
+		modulePx: opts.Size / len(bits),
+		width:    opts.Size,
+		height:   opts.Size,
+	}
+	tile.offset = (opts.Size - tile.modulePx*len(bits)) / 2
+
+	if caption {
+		if account.Issuer != "" {
+			tile.lines = append(tile.lines, account.Issuer)
+		}
+		tile.lines = append(tile.lines, account.Name)
+		tile.fontSize = max(10, opts.Size/16)
+		tile.height += captionHeight(tile)
+
+		face, err := captionFace(tile.fontSize)
+		if err != nil {
+			return qrTile{}, err
+		}
+		defer face.Close()
+
+		// SVG text is measured with the same font, which is close enough to
+		// the viewer's sans-serif to keep captions inside their code's width.
+		margin := fixed.I(tile.fontSize / 2)
+		for i, line := range tile.lines {
+			tile.lines[i] = truncateText(face, line, fixed.I(tile.width)-2*margin)
+		}
+	}
+	return tile, nil
+}
+
+func captionHeight(tile qrTile) int {
+	return len(tile.lines)*tile.fontSize*5/4 + tile.fontSize/2
+}
+
+// QRImage encodes the code of one account in the chosen format.
+func QRImage(account decoder.Account, opts QRImageOptions) ([]byte, error) {
+	tile, err := layoutTile(account, opts, opts.Caption)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.Format == ImageSVG {
+		var b bytes.Buffer
+		fmt.Fprintf(&b, svgHeader, tile.width, tile.height)
+		writeSVGTile(&b, tile, opts)
+		b.WriteString("</svg>\n")
+		return b.Bytes(), nil
+	}
+
+	img := image.NewNRGBA(image.Rect(0, 0, tile.width, tile.height))
+	if err := drawTile(img, image.Point{}, tile, opts); err != nil {
+		return nil, err
+	}
+	return encodePNG(img)
+}
+
+// ContactSheet lays the codes of all accounts out in a grid, each with its
+// caption, so that they can be printed or scanned from a single image.
+func ContactSheet(accounts []decoder.Account, opts QRImageOptions, columns int) ([]byte, error) {
+	if columns < 1 {
+		return nil, fmt.Errorf("invalid number of columns %d", columns)
+	}
+	if len(accounts) == 0 {
+		return nil, fmt.Errorf("no accounts to put on the contact sheet")
+	}
+	columns = min(columns, len(accounts))
+
+	tiles := make([]qrTile, len(accounts))
+	cellHeight := 0
+	for i, account := range accounts {
+		tile, err := layoutTile(account, opts, true)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate QR code for account '%s': %w", account.Name, err)
+		}
+		tiles[i] = tile
+		cellHeight = max(cellHeight, tile.height)
+	}
+
+	rows := (len(tiles) + columns - 1) / columns
+	width, height := columns*opts.Size, rows*cellHeight
+	if width > maxSheetPixels/height {
+		return nil, fmt.Errorf("a contact sheet of %dx%d pixels is too large: use a smaller --size or fewer accounts", width, height)
+	}
+	cell := func(i int) image.Point {
+		return image.Pt(i%columns*opts.Size, i/columns*cellHeight)
+	}
+
+	if opts.Format == ImageSVG {
+		var b bytes.Buffer
+		fmt.Fprintf(&b, svgHeader, width, height)
+		fmt.Fprintf(&b, `<rect width="%d" height="%d" fill="%s"/>`+"\n", width, height, svgColor(opts.Background))
+		for i, tile := range tiles {
+			at := cell(i)
+			fmt.Fprintf(&b, `<svg x="%d" y="%d" width="%d" height="%d">`+"\n", at.X, at.Y, tile.width, tile.height)
+			writeSVGTile(&b, tile, opts)
+			b.WriteString("</svg>\n")
+		}
+		b.WriteString("</svg>\n")
+		return b.Bytes(), nil
+	}
+
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(img, img.Bounds(), image.NewUniform(opts.Background), image.Point{}, draw.Src)
+	for i, tile := range tiles {
+		if err := drawTile(img, cell(i), tile, opts); err != nil {
+			return nil, err
+		}
+	}
+	return encodePNG(img)
+}
+
+func drawTile(img draw.Image, at image.Point, tile qrTile, opts QRImageOptions) error {
+	bounds := image.Rect(0, 0, tile.width, tile.height).Add(at)
+	draw.Draw(img, bounds, image.NewUniform(opts.Background), image.Point{}, draw.Src)
+
+	fg := image.NewUniform(opts.Foreground)
+	for y, row := range tile.bits {
+		for x, dark := range row {
+			if !dark {
+				continue
+			}
+			module := image.Rect(0, 0, tile.modulePx, tile.modulePx).
+				Add(at).Add(image.Pt(tile.offset+x*tile.modulePx, tile.offset+y*tile.modulePx))
+			draw.Draw(img, module, fg, image.Point{}, draw.Src)
+		}
+	}
+
+	if len(tile.lines) == 0 {
+		return nil
+	}
+
+	face, err := captionFace(tile.fontSize)
+	if err != nil {
+		return err
+	}
+	defer face.Close()
+
+	drawer := &font.Drawer{Dst: img, Src: fg, Face: face}
+	for i, line := range tile.lines {
+		lineWidth := drawer.MeasureString(line)
+		drawer.Dot = fixed.Point26_6{
+			X: fixed.I(at.X) + (fixed.I(tile.width)-lineWidth)/2,
+			Y: fixed.I(at.Y + tile.width + (i+1)*tile.fontSize*5/4),
+		}
+		drawer.DrawString(line)
+	}
+	return nil
+}
+
+var captionFont = sync.OnceValues(func() (*opentype.Font, error) {
+	return opentype.Parse(goregular.TTF)
+})
+
+func captionFace(size int) (font.Face, error) {
+	f, err := captionFont()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load caption font: %w", err)
+	}
+	return opentype.NewFace(f, &opentype.FaceOptions{Size: float64(size), DPI: 72, Hinting: font.HintingFull})
+}
+
+// truncateText shortens s with an ellipsis until it is at most width wide.
+func truncateText(face font.Face, s string, width fixed.Int26_6) string {
+	if font.MeasureString(face, s) <= width {
+		return s
+	}
+	runes := []rune(s)
+	for len(runes) > 0 {
+		runes = runes[:len(runes)-1]
+		if t := string(runes) + "…"; font.MeasureString(face, t) <= width {
+			return t
+		}
+	}
+	return ""
+}
+
+const svgHeader = `<?xml version="1.0" encoding="UTF-8"?>
+<svg xmlns="http://www.w3.org/2000/svg" width="%[1]d" height="%[2]d" viewBox="0 0 %[1]d %[2]d" shape-rendering="crispEdges">
+`
+
+// writeSVGTile draws the dark modules as a single path, one horizontal run
+// of modules per subpath.
+func writeSVGTile(b *bytes.Buffer, tile qrTile, opts QRImageOptions) {
+	fmt.Fprintf(b, `<rect width="%d" height="%d" fill="%s"/>`+"\n", tile.width, tile.height, svgColor(opts.Background))
+
+	fmt.Fprintf(b, `<path fill="%s" d="`, svgColor(opts.Foreground))
+	for y, row := range tile.bits {
+		for x := 0; x < len(row); {
+			if !row[x] {
+				x++
+				continue
+			}
+			run := 0
+			for x+run < len(row) && row[x+run] {
+				run++
+			}
+			fmt.Fprintf(b, "M%d %dh%dv%dh-%dz",
+				tile.offset+x*tile.modulePx, tile.offset+y*tile.modulePx,
+				run*tile.modulePx, tile.modulePx, run*tile.modulePx)
+			x += run
+		}
+	}
+	b.WriteString("\"/>\n")
+
+	for i, line := range tile.lines {
+		fmt.Fprintf(b, `<text x="%d" y="%d" font-family="sans-serif" font-size="%d" text-anchor="middle" fill="%s">`,
+			tile.width/2, tile.width+(i+1)*tile.fontSize*5/4, tile.fontSize, svgColor(opts.Foreground))
+		xml.EscapeText(b, []byte(line))
+		b.WriteString("</text>\n")
+	}
+}
+
+func svgColor(c color.NRGBA) string {
+	if c.A == 0xff {
+		return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+	}
+	return fmt.Sprintf("rgba(%d,%d,%d,%.3g)", c.R, c.G, c.B, float64(c.A)/255)
+}
+
+func encodePNG(img image.Image) ([]byte, error) {
+	var b bytes.Buffer
+	if err := png.Encode(&b, img); err != nil {
+		return nil, fmt.Errorf("failed to encode PNG: %w", err)
+	}
+	return b.Bytes(), nil
+}
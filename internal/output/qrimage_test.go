@@ -0,0 +1,175 @@
+package output
+
+import (
+	"bytes"
+	"encoding/xml"
+	"image/color"
+	"image/png"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/Zaphkiel-Ivanovna/GoogleAuthExtractor/internal/decoder"
+	"github.com/makiuchi-d/gozxing"
+	zxingqr "github.com/makiuchi-d/gozxing/qrcode"
+	"github.com/skip2/go-qrcode"
+)
+
+var testAccount = decoder.Account{
+	Name:       "alice@example.com",
+	Issuer:     "Example & Co",
+	TOTPSecret: "JBSWY3DPEHPK3PXP",
+	Type:       "TOTP",
+}
+
+func TestParseColor(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected color.NRGBA
+		wantErr  bool
+	}{
+		{"#000", color.NRGBA{A: 0xff}, false},
+		{"#1a2b3c", color.NRGBA{R: 0x1a, G: 0x2b, B: 0x3c, A: 0xff}, false},
+		{"fff8", color.NRGBA{R: 0xff, G: 0xff, B: 0xff, A: 0x88}, false},
+		{"#11223344", color.NRGBA{R: 0x11, G: 0x22, B: 0x33, A: 0x44}, false},
+		{"#12345", color.NRGBA{}, true},
+		{"black", color.NRGBA{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseColor(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Expected error %v, got %v", tt.wantErr, err)
+			}
+			if got != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestQRImagePNG(t *testing.T) {
+	opts := DefaultQRImageOptions()
+	opts.Size = 300
+	opts.QuietZone = 2
+	opts.Level = qrcode.High
+	opts.Foreground = color.NRGBA{R: 0x20, G: 0x30, B: 0x80, A: 0xff}
+	opts.Caption = true
+
+	data, err := QRImage(testAccount, opts)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("Failed to decode PNG: %v", err)
+	}
+	if b := img.Bounds(); b.Dx() != 300 || b.Dy() <= 300 {
+		t.Errorf("Expected a 300 pixel wide image with a caption below, got %v", b)
+	}
+
+	bmp, err := gozxing.NewBinaryBitmapFromImage(img)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	result, err := zxingqr.NewQRCodeReader().Decode(bmp, nil)
+	if err != nil {
+		t.Fatalf("Failed to scan the generated code: %v", err)
+	}
+	if expected := generateOtpAuthURI(testAccount); result.GetText() != expected {
+		t.Errorf("Expected %s, got %s", expected, result.GetText())
+	}
+}
+
+func TestQRImageSVG(t *testing.T) {
+	opts := DefaultQRImageOptions()
+	opts.Format = ImageSVG
+	opts.Caption = true
+
+	for name, render := range map[string]func() ([]byte, error){
+		"Single code":   func() ([]byte, error) { return QRImage(testAccount, opts) },
+		"Contact sheet": func() ([]byte, error) { return ContactSheet([]decoder.Account{testAccount, testAccount}, opts, 4) },
+	} {
+		t.Run(name, func(t *testing.T) {
+			data, err := render()
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			dec := xml.NewDecoder(bytes.NewReader(data))
+			for {
+				_, err := dec.Token()
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					t.Fatalf("Generated SVG is not well-formed: %v", err)
+				}
+			}
+			if !strings.Contains(string(data), "Example &amp; Co") {
+				t.Errorf("Expected an escaped caption in the SVG")
+			}
+		})
+	}
+}
+
+func TestContactSheetWithoutAccounts(t *testing.T) {
+	for _, format := range []ImageFormat{ImagePNG, ImageSVG} {
+		opts := DefaultQRImageOptions()
+		opts.Format = format
+		if _, err := ContactSheet(nil, opts, 4); err == nil {
+			t.Errorf("Expected an error for an empty %s contact sheet", format)
+		}
+	}
+}
+
+func TestQRImageTooSmall(t *testing.T) {
+	opts := DefaultQRImageOptions()
+	opts.Size = 25
+
+	if _, err := QRImage(testAccount, opts); err == nil {
+		t.Errorf("Expected error for a size smaller than the code")
+	}
+}
+
+func TestQRImageTooLarge(t *testing.T) {
+	opts := DefaultQRImageOptions()
+	opts.Size = MaxQRImageSize + 1
+	if _, err := QRImage(testAccount, opts); err == nil {
+		t.Errorf("Expected error for a size above %d", MaxQRImageSize)
+	}
+
+	opts = DefaultQRImageOptions()
+	opts.QuietZone = 1 << 30
+	if _, err := QRImage(testAccount, opts); err == nil {
+		t.Errorf("Expected error for a quiet zone wider than the code")
+	}
+
+	opts = DefaultQRImageOptions()
+	opts.Size = MaxQRImageSize
+	accounts := []decoder.Account{testAccount, testAccount, testAccount, testAccount}
+	if _, err := ContactSheet(accounts, opts, 4); err == nil {
+		t.Errorf("Expected error for a contact sheet above %d pixels", maxSheetPixels)
+	}
+}
+
+func TestParseRecoveryLevel(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected qrcode.RecoveryLevel
+	}{
+		{"L", qrcode.Low},
+		{"medium", qrcode.Medium},
+		{"Q", qrcode.High},
+		{"high", qrcode.Highest},
+		{"H", qrcode.Highest},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseRecoveryLevel(tt.input)
+		if err != nil || got != tt.expected {
+			t.Errorf("%s: expected level %v, got %v (%v)", tt.input, tt.expected, got, err)
+		}
+	}
+}
@@ -0,0 +1,309 @@
+package output
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/Zaphkiel-Ivanovna/GoogleAuthExtractor/internal/decoder"
+	"github.com/Zaphkiel-Ivanovna/GoogleAuthExtractor/internal/logging"
+	"github.com/skip2/go-qrcode"
+	"golang.org/x/term"
+)
+
+type TerminalMode string
+
+const (
+	TerminalAuto      TerminalMode = "auto"
+	TerminalHalfBlock TerminalMode = "halfblock"
+	TerminalSixel     TerminalMode = "sixel"
+	TerminalKitty     TerminalMode = "kitty"
+)
+
+func ParseTerminalMode(s string) (TerminalMode, error) {
+	switch m := TerminalMode(s); m {
+	case TerminalAuto, TerminalHalfBlock, TerminalSixel, TerminalKitty:
+		return m, nil
+	}
+	return "", fmt.Errorf("invalid terminal QR mode '%s': expected auto, halfblock, sixel or kitty", s)
+}
+
+type TerminalOptions struct {
+	Mode TerminalMode
+	// Invert draws dark modules with the text colour, for terminals with a
+	// light background. By default the text colour is assumed to be light.
+	Invert bool
+	// Paged shows one code at a time and waits for a key between them.
+	Paged bool
+}
+
+// Lines kept free around a code for the account name and details.
+const terminalReservedLines = 4
+
+// Pixels per module of sixel and kitty images.
+const graphicsModuleSize = 6
+
+// terminalSize returns the size of the terminal on stdout in characters, or
+// ok false when stdout is not a terminal.
+func terminalSize() (cols, rows int, ok bool) {
+	cols, rows, err := term.GetSize(int(os.Stdout.Fd()))
+	if err != nil || cols <= 0 || rows <= 0 {
+		return 0, 0, false
+	}
+	return cols, rows, true
+}
+
+// detectGraphics picks the inline image protocol of the terminal from its
+// environment. Terminal multiplexers are assumed to support neither.
+func detectGraphics() TerminalMode {
+	if os.Getenv("TMUX") != "" || strings.HasPrefix(os.Getenv("TERM"), "screen") {
+		return TerminalHalfBlock
+	}
+
+	termName := os.Getenv("TERM")
+	switch {
+	case os.Getenv("KITTY_WINDOW_ID") != "", termName == "xterm-kitty", termName == "xterm-ghostty",
+		os.Getenv("TERM_PROGRAM") == "WezTerm", os.Getenv("TERM_PROGRAM") == "ghostty":
+		return TerminalKitty
+	case strings.Contains(termName, "sixel"), termName == "foot", strings.HasPrefix(termName, "mlterm"),
+		termName == "yaft-256color":
+		return TerminalSixel
+	}
+	return TerminalHalfBlock
+}
+
+func (opts TerminalOptions) resolve(isTerminal bool) TerminalMode {
+	if opts.Mode != TerminalAuto && opts.Mode != "" {
+		return opts.Mode
+	}
+	if !isTerminal {
+		return TerminalHalfBlock
+	}
+	return detectGraphics()
+}
+
+// writeTerminalQR draws the QR code of uri on w. With the half-block renderer
+// the code is shrunk to fit the terminal when needed, and a warning is shown
+// when it cannot be made small enough to stay scannable.
+func writeTerminalQR(w io.Writer, uri, label string, opts TerminalOptions) error {
+	cols, rows, isTerminal := terminalSize()
+
+	qr, warning, err := renderTerminalQR(uri, label, opts, cols, rows-terminalReservedLines, isTerminal)
+	if err != nil {
+		return err
+	}
+	if warning != "" {
+		logging.Warnf("%s", warning)
+	}
+
+	_, err = io.WriteString(w, qr)
+	return err
+}
+
+// renderTerminalQR returns the QR code of uri drawn for a terminal of cols x
+// rows characters, and a warning when a half-block code does not fit.
+func renderTerminalQR(uri, label string, opts TerminalOptions, cols, rows int, isTerminal bool) (qr, warning string, err error) {
+	switch opts.resolve(isTerminal) {
+	case TerminalSixel:
+		bits, err := qrBitmap(uri, qrcode.Medium, 4)
+		if err != nil {
+			return "", "", err
+		}
+		return encodeSixel(bits, graphicsModuleSize) + "\n", "", nil
+	case TerminalKitty:
+		bits, err := qrBitmap(uri, qrcode.Medium, 4)
+		if err != nil {
+			return "", "", err
+		}
+		var b strings.Builder
+		err = writeKitty(&b, bits, graphicsModuleSize)
+		return b.String(), "", err
+	}
+
+	bits, fits, err := fitQR(uri, cols, rows, isTerminal)
+	if err != nil {
+		return "", "", err
+	}
+	if !fits {
+		warning = fmt.Sprintf("The QR code for %s needs %dx%d characters but the terminal is %dx%d: it may not scan. "+
+			"Enlarge the window, reduce the font size or use --terminal-qr=sixel or kitty.",
+			label, len(bits), (len(bits)+1)/2+terminalReservedLines, cols, rows+terminalReservedLines)
+	}
+	return renderHalfBlocks(bits, opts.Invert), warning, nil
+}
+
+// qrBitmap encodes uri and surrounds it with a quiet zone of border modules.
+// true is a dark module.
+func qrBitmap(uri string, level qrcode.RecoveryLevel, border int) ([][]bool, error) {
+	qr, err := qrcode.New(uri, level)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate QR code: %w", err)
+	}
+	qr.DisableBorder = true
+	core := qr.Bitmap()
+
+	size := len(core) + 2*border
+	bits := make([][]bool, size)
+	for y := range bits {
+		bits[y] = make([]bool, size)
+		if y >= border && y < border+len(core) {
+			copy(bits[y][border:], core[y-border])
+		}
+	}
+	return bits, nil
+}
+
+// fitQR returns the largest rendering of uri that fits in cols x rows
+// characters, reducing the quiet zone and then the error correction level.
+// When nothing fits, the smallest rendering is returned with fits false.
+func fitQR(uri string, cols, rows int, isTerminal bool) (bits [][]bool, fits bool, err error) {
+	if !isTerminal {
+		bits, err := qrBitmap(uri, qrcode.Medium, 4)
+		return bits, true, err
+	}
+
+	for _, level := range []qrcode.RecoveryLevel{qrcode.Medium, qrcode.Low} {
+		for _, border := range []int{4, 2, 1} {
+			bits, err = qrBitmap(uri, level, border)
+			if err != nil {
+				return nil, false, err
+			}
+			if len(bits) <= cols && (len(bits)+1)/2 <= rows {
+				return bits, true, nil
+			}
+		}
+	}
+	return bits, false, nil
+}
+
+// renderHalfBlocks draws two rows of modules per line of text. Light modules
+// are drawn with the text colour unless invert is set.
+func renderHalfBlocks(bits [][]bool, invert bool) string {
+	filled := func(y, x int) bool {
+		dark := y < len(bits) && bits[y][x]
+		return dark == invert
+	}
+
+	var b strings.Builder
+	for y := 0; y < len(bits); y += 2 {
+		for x := range bits[y] {
+			switch top, bottom := filled(y, x), filled(y+1, x); {
+			case top && bottom:
+				b.WriteString("█")
+			case top:
+				b.WriteString("▀")
+			case bottom:
+				b.WriteString("▄")
+			default:
+				b.WriteString(" ")
+			}
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// encodeSixel draws the bitmap as a black-on-white DEC sixel image, scale
+// pixels per module.
+func encodeSixel(bits [][]bool, scale int) string {
+	size := len(bits) * scale
+
+	var b strings.Builder
+	b.WriteString("\x1bPq")
+	fmt.Fprintf(&b, "\"1;1;%d;%d", size, size)
+	b.WriteString("#0;2;100;100;100#1;2;0;0;0")
+
+	for band := 0; band < size; band += 6 {
+		for c, dark := range []bool{false, true} {
+			fmt.Fprintf(&b, "#%d", c)
+
+			var run byte
+			count := 0
+			flush := func() {
+				switch {
+				case count > 3:
+					fmt.Fprintf(&b, "!%d%c", count, run)
+				case count > 0:
+					b.WriteString(strings.Repeat(string(run), count))
+				}
+			}
+
+			for x := 0; x < size; x++ {
+				var sixel byte
+				for i := 0; i < 6 && band+i < size; i++ {
+					if bits[(band+i)/scale][x/scale] == dark {
+						sixel |= 1 << i
+					}
+				}
+				ch := 63 + sixel
+				if ch != run {
+					flush()
+					run, count = ch, 0
+				}
+				count++
+			}
+			flush()
+			b.WriteString("$")
+		}
+		b.WriteString("-")
+	}
+
+	b.WriteString("\x1b\\")
+	return b.String()
+}
+
+// writeKitty sends the bitmap as a PNG with the kitty graphics protocol.
+func writeKitty(w io.Writer, bits [][]bool, scale int) error {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, bitmapImage(bits, scale)); err != nil {
+		return fmt.Errorf("failed to encode QR code: %w", err)
+	}
+	data := base64.StdEncoding.EncodeToString(buf.Bytes())
+
+	const chunkSize = 4096
+	for i := 0; i < len(data); i += chunkSize {
+		chunk := data[i:min(i+chunkSize, len(data))]
+		more := 0
+		if i+chunkSize < len(data) {
+			more = 1
+		}
+
+		control := fmt.Sprintf("m=%d", more)
+		if i == 0 {
+			control = "a=T,f=100," + control
+		}
+		if _, err := fmt.Fprintf(w, "\x1b_G%s;%s\x1b\\", control, chunk); err != nil {
+			return err
+		}
+	}
+
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// bitmapImage draws the bitmap black on white, scale pixels per module.
+func bitmapImage(bits [][]bool, scale int) *image.Paletted {
+	size := len(bits) * scale
+	img := image.NewPaletted(image.Rect(0, 0, size, size), color.Palette{color.White, color.Black})
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			if bits[y/scale][x/scale] {
+				img.Pix[y*img.Stride+x] = 1
+			}
+		}
+	}
+	return img
+}
+
+func displayName(account decoder.Account) string {
+	if account.Issuer != "" {
+		return fmt.Sprintf("%s (%s)", account.Issuer, account.Name)
+	}
+	return account.Name
+}
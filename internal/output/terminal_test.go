@@ -0,0 +1,158 @@
+package output
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/skip2/go-qrcode"
+)
+
+const testOtpAuthURI = "otpauth://totp/alice%40example.com?secret=JBSWY3DPEHPK3PXP&issuer=Example"
+
+// parseHalfBlocks turns rendered text back into a bitmap of dark modules.
+func parseHalfBlocks(text string, invert bool) [][]bool {
+	var bits [][]bool
+	for _, line := range strings.Split(strings.TrimSuffix(text, "\n"), "\n") {
+		var top, bottom []bool
+		for _, r := range line {
+			t := r == '█' || r == '▀'
+			b := r == '█' || r == '▄'
+			top = append(top, t == invert)
+			bottom = append(bottom, b == invert)
+		}
+		bits = append(bits, top, bottom)
+	}
+	return bits
+}
+
+func TestRenderHalfBlocks(t *testing.T) {
+	for _, invert := range []bool{false, true} {
+		bits, err := qrBitmap(testOtpAuthURI, qrcode.Medium, 4)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		parsed := parseHalfBlocks(renderHalfBlocks(bits, invert), invert)
+		if len(bits)%2 == 1 {
+			// The padding row below an odd bitmap is drawn as quiet zone.
+			if last := parsed[len(parsed)-1]; strings.Contains(boolString(last), "#") {
+				t.Errorf("Expected padding row to be light, got %s", boolString(last))
+			}
+			parsed = parsed[:len(parsed)-1]
+		}
+		if !reflect.DeepEqual(parsed, bits) {
+			t.Errorf("Half-block rendering (invert=%v) does not match the QR bitmap", invert)
+		}
+	}
+}
+
+func boolString(row []bool) string {
+	var b strings.Builder
+	for _, dark := range row {
+		if dark {
+			b.WriteByte('#')
+		} else {
+			b.WriteByte('.')
+		}
+	}
+	return b.String()
+}
+
+func TestFitQR(t *testing.T) {
+	full, _ := qrBitmap(testOtpAuthURI, qrcode.Medium, 4)
+
+	tests := []struct {
+		name       string
+		cols, rows int
+		size       int
+		fits       bool
+	}{
+		{"Large terminal", 200, 100, len(full), true},
+		{"Smaller quiet zone", len(full) - 4, 100, len(full) - 4, true},
+		{"Too small", 10, 5, -1, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bits, fits, err := fitQR(testOtpAuthURI, tt.cols, tt.rows, true)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if fits != tt.fits {
+				t.Errorf("Expected fits=%v, got %v", tt.fits, fits)
+			}
+			if tt.size > 0 && len(bits) != tt.size {
+				t.Errorf("Expected %d modules, got %d", tt.size, len(bits))
+			}
+		})
+	}
+}
+
+// decodeSixel reads back the pixels set by colour register 1.
+func decodeSixel(t *testing.T, data string, size int) [][]bool {
+	t.Helper()
+
+	if !strings.HasPrefix(data, "\x1bPq") || !strings.HasSuffix(data, "\x1b\\") {
+		t.Fatalf("Missing sixel introducer or terminator")
+	}
+	body := strings.TrimSuffix(strings.TrimPrefix(data, "\x1bPq"), "\x1b\\")
+	body = body[strings.Index(body, "#0;2;100;100;100#1;2;0;0;0")+len("#0;2;100;100;100#1;2;0;0;0"):]
+
+	pixels := make([][]bool, size)
+	for i := range pixels {
+		pixels[i] = make([]bool, size)
+	}
+
+	band, x, colour := 0, 0, 0
+	for i := 0; i < len(body); i++ {
+		switch c := body[i]; {
+		case c == '#':
+			colour = int(body[i+1] - '0')
+			i++
+		case c == '$':
+			x = 0
+		case c == '-':
+			band += 6
+			x = 0
+		default:
+			count := 1
+			if c == '!' {
+				end := i + 1
+				for body[end] >= '0' && body[end] <= '9' {
+					end++
+				}
+				count, _ = strconv.Atoi(body[i+1 : end])
+				i = end
+				c = body[i]
+			}
+			for n := 0; n < count; n++ {
+				for bit := 0; bit < 6; bit++ {
+					if (c-63)&(1<<bit) != 0 && colour == 1 && band+bit < size {
+						pixels[band+bit][x] = true
+					}
+				}
+				x++
+			}
+		}
+	}
+	return pixels
+}
+
+func TestEncodeSixel(t *testing.T) {
+	bits, err := qrBitmap(testOtpAuthURI, qrcode.Low, 2)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	const scale = 3
+	pixels := decodeSixel(t, encodeSixel(bits, scale), len(bits)*scale)
+	for y, row := range pixels {
+		for x, dark := range row {
+			if dark != bits[y/scale][x/scale] {
+				t.Fatalf("Pixel (%d, %d): expected %v, got %v", x, y, bits[y/scale][x/scale], dark)
+			}
+		}
+	}
+}
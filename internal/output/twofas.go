@@ -0,0 +1,143 @@
+package output
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/Zaphkiel-Ivanovna/GoogleAuthExtractor/internal/decoder"
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// twoFASBackup is a 2FAS backup file. Encrypted backups leave Services
+// empty and carry them in ServicesEncrypted instead.
+type twoFASBackup struct {
+	Services          []twoFASService `json:"services"`
+	Groups            []struct{}      `json:"groups"`
+	UpdatedAt         int64           `json:"updatedAt"`
+	SchemaVersion     int             `json:"schemaVersion"`
+	AppVersionCode    int             `json:"appVersionCode"`
+	AppVersionName    string          `json:"appVersionName"`
+	AppOrigin         string          `json:"appOrigin"`
+	ServicesEncrypted string          `json:"servicesEncrypted,omitempty"`
+	Reference         string          `json:"reference,omitempty"`
+}
+
+type twoFASService struct {
+	Name      string      `json:"name"`
+	Secret    string      `json:"secret"`
+	UpdatedAt int64       `json:"updatedAt"`
+	OTP       twoFASOTP   `json:"otp"`
+	Order     twoFASOrder `json:"order"`
+}
+
+type twoFASOTP struct {
+	Label     string `json:"label"`
+	Account   string `json:"account"`
+	Issuer    string `json:"issuer,omitempty"`
+	Digits    int    `json:"digits"`
+	Period    int    `json:"period"`
+	Algorithm string `json:"algorithm"`
+	Counter   int64  `json:"counter"`
+	TokenType string `json:"tokenType"`
+	Source    string `json:"source"`
+}
+
+type twoFASOrder struct {
+	Position int `json:"position"`
+}
+
+const (
+	twoFASSchemaVersion = 4
+	twoFASIterations    = 10000
+	twoFASSaltSize      = 256
+	twoFASNonceSize     = 12
+	twoFASKeySize       = 32
+)
+
+// twoFASReference is the text 2FAS encrypts next to the services to check
+// the password before decrypting them.
+const twoFASReference = "tRViSsLKzd86Hprh4ceC2OP7xazn4rrt4xhfEUbOjxLX8Rc3mkISXE0lWbmnWfggogbBJhtYgpK6fMl1D6mtsy92R3HkdGfwuXbzLebqVFJsR7IZ2w58t938iymwG4824igYy1wi6n2WDpO1Q1P69zwJGs2F5a1qP4MyIiDSD7NCV2OvidXQCBnDlGfmz0f1BQySRkkt4ryiJeCjD2o4QsveJ9uDBUn8ELyOrESv5R5DMDkD4iAF8TXU7KyoJujd"
+
+func twoFASServices(accounts []decoder.Account, updated int64) []twoFASService {
+	services := make([]twoFASService, len(accounts))
+	for i, account := range accounts {
+		name := account.Issuer
+		if name == "" {
+			name = account.Name
+		}
+
+		services[i] = twoFASService{
+			Name:      name,
+			Secret:    account.Base32(),
+			UpdatedAt: updated,
+			OTP: twoFASOTP{
+				Label:     account.Name,
+				Account:   account.Name,
+				Issuer:    account.Issuer,
+				Digits:    account.DigitCount(),
+				Period:    period(),
+				Algorithm: account.AlgorithmOrDefault(),
+				Counter:   account.Counter,
+				TokenType: otpType(account),
+				Source:    "Link",
+			},
+			Order: twoFASOrder{Position: i},
+		}
+	}
+	return services
+}
+
+// encode2FAS returns a 2FAS backup. Encrypted backups hold the services as
+// "ciphertext:salt:nonce" in base64, encrypted with AES-256-GCM under a key
+// derived with PBKDF2-HMAC-SHA256.
+func encode2FAS(accounts []decoder.Account, password []byte, now time.Time) ([]byte, error) {
+	backup := twoFASBackup{
+		Services:       twoFASServices(accounts, now.UnixMilli()),
+		Groups:         []struct{}{},
+		UpdatedAt:      now.UnixMilli(),
+		SchemaVersion:  twoFASSchemaVersion,
+		AppVersionCode: 5000029,
+		AppVersionName: "5.0.0",
+		AppOrigin:      "android",
+	}
+
+	if len(password) > 0 {
+		services, err := json.Marshal(backup.Services)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal account data: %w", err)
+		}
+
+		salt, err := randomBytes(twoFASSaltSize)
+		if err != nil {
+			return nil, err
+		}
+		key := pbkdf2.Key(password, salt, twoFASIterations, twoFASKeySize, sha256.New)
+
+		if backup.ServicesEncrypted, err = seal2FAS(key, salt, services); err != nil {
+			return nil, err
+		}
+		if backup.Reference, err = seal2FAS(key, salt, []byte(twoFASReference)); err != nil {
+			return nil, err
+		}
+		backup.Services = []twoFASService{}
+	}
+
+	data, err := json.MarshalIndent(backup, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal account data: %w", err)
+	}
+	return data, nil
+}
+
+func seal2FAS(key, salt, plaintext []byte) (string, error) {
+	nonce, ciphertext, err := sealGCM(key, plaintext, twoFASNonceSize)
+	if err != nil {
+		return "", fmt.Errorf("failed to encrypt 2FAS backup: %w", err)
+	}
+
+	enc := base64.StdEncoding
+	return enc.EncodeToString(ciphertext) + ":" + enc.EncodeToString(salt) + ":" + enc.EncodeToString(nonce), nil
+}
@@ -0,0 +1,16 @@
+package secmem
+
+import (
+	"fmt"
+
+	"golang.org/x/sys/unix"
+)
+
+// disableDumpable also keeps other processes of the same user from reading
+// the process memory through ptrace or /proc/<pid>/mem.
+func disableDumpable() error {
+	if err := unix.Prctl(unix.PR_SET_DUMPABLE, 0, 0, 0, 0); err != nil {
+		return fmt.Errorf("failed to mark the process as not dumpable: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,7 @@
+//go:build unix && !linux
+
+package secmem
+
+func disableDumpable() error {
+	return nil
+}
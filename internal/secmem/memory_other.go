@@ -0,0 +1,12 @@
+//go:build !unix
+
+package secmem
+
+func alloc(size int) ([]byte, func(), bool) {
+	return make([]byte, size), nil, false
+}
+
+// DisableCoreDumps does nothing on platforms without core dump limits.
+func DisableCoreDumps() error {
+	return nil
+}
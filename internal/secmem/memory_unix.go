@@ -0,0 +1,40 @@
+//go:build unix
+
+package secmem
+
+import (
+	"fmt"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// alloc maps whole pages for size bytes and locks them. It falls back to
+// the Go heap when the mapping fails.
+func alloc(size int) ([]byte, func(), bool) {
+	page := os.Getpagesize()
+	mapped, err := unix.Mmap(-1, 0, (size+page-1)/page*page, unix.PROT_READ|unix.PROT_WRITE, unix.MAP_ANON|unix.MAP_PRIVATE)
+	if err != nil {
+		return make([]byte, size), nil, false
+	}
+
+	// Locking fails when RLIMIT_MEMLOCK is exhausted; the buffer is still
+	// off the Go heap and wiped.
+	locked := unix.Mlock(mapped) == nil
+	free := func() {
+		if locked {
+			unix.Munlock(mapped)
+		}
+		unix.Munmap(mapped)
+	}
+	return mapped[:size:size], free, locked
+}
+
+// DisableCoreDumps stops the process from writing core dumps, which would
+// contain the secrets in memory.
+func DisableCoreDumps() error {
+	if err := unix.Setrlimit(unix.RLIMIT_CORE, &unix.Rlimit{}); err != nil {
+		return fmt.Errorf("failed to disable core dumps: %w", err)
+	}
+	return disableDumpable()
+}
@@ -0,0 +1,107 @@
+// Package secmem holds secrets in buffers that are kept out of swap where
+// the platform allows it and wiped when no longer needed.
+package secmem
+
+import (
+	"runtime"
+	"slices"
+	"sync"
+
+	"github.com/Zaphkiel-Ivanovna/GoogleAuthExtractor/internal/logging"
+)
+
+// Buffer is a byte buffer for a secret. Its memory is allocated outside the
+// Go heap and locked into RAM when possible, so that the garbage collector
+// never copies it and it is not written to swap.
+type Buffer struct {
+	b      []byte
+	free   func()
+	locked bool
+}
+
+var (
+	mu      sync.Mutex
+	buffers []*Buffer
+	warned  bool
+)
+
+// New returns a zeroed buffer of size bytes. The buffer is wiped by WipeAll
+// if Destroy is not called first.
+func New(size int) *Buffer {
+	buf := &Buffer{}
+	if size > 0 {
+		buf.b, buf.free, buf.locked = alloc(size)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if size > 0 && !buf.locked && !warned {
+		warned = true
+		logging.Debugf("Secrets could not be locked in memory and may be swapped to disk")
+	}
+	buffers = append(buffers, buf)
+	return buf
+}
+
+// FromBytes moves src into a new buffer and wipes src.
+func FromBytes(src []byte) *Buffer {
+	buf := New(len(src))
+	copy(buf.b, src)
+	Wipe(src)
+	return buf
+}
+
+// Bytes returns the contents of the buffer. The slice must not be kept
+// after Destroy or WipeAll.
+func (b *Buffer) Bytes() []byte {
+	if b == nil {
+		return nil
+	}
+	return b.b
+}
+
+// Locked reports whether the buffer is locked in memory.
+func (b *Buffer) Locked() bool {
+	return b != nil && b.locked
+}
+
+// Destroy wipes the buffer and releases its memory.
+func (b *Buffer) Destroy() {
+	if b == nil {
+		return
+	}
+	b.destroy()
+
+	mu.Lock()
+	defer mu.Unlock()
+	buffers = slices.DeleteFunc(buffers, func(buf *Buffer) bool { return buf == b })
+}
+
+func (b *Buffer) destroy() {
+	if b.b == nil {
+		return
+	}
+	Wipe(b.b)
+	if b.free != nil {
+		b.free()
+	}
+	b.b, b.free = nil, nil
+}
+
+// WipeAll destroys every buffer created so far.
+func WipeAll() {
+	mu.Lock()
+	defer mu.Unlock()
+
+	for _, buf := range buffers {
+		buf.destroy()
+	}
+	buffers = nil
+}
+
+// Wipe overwrites b with zeros.
+func Wipe(b []byte) {
+	clear(b)
+	// Keep the compiler from treating the writes as dead stores.
+	runtime.KeepAlive(b)
+}
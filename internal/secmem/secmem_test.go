@@ -0,0 +1,26 @@
+package secmem
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestBuffer(t *testing.T) {
+	src := []byte("12345678901234567890")
+	buf := FromBytes(src)
+
+	if !bytes.Equal(src, make([]byte, len(src))) {
+		t.Errorf("Expected the source to be wiped, got %q", src)
+	}
+	if string(buf.Bytes()) != "12345678901234567890" {
+		t.Errorf("Expected the secret in the buffer, got %q", buf.Bytes())
+	}
+
+	// The memory is unmapped by WipeAll, so the old contents cannot be
+	// read back to check that they were wiped.
+	WipeAll()
+	if buf.Bytes() != nil {
+		t.Errorf("Expected no contents after WipeAll")
+	}
+	buf.Destroy()
+}
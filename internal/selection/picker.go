@@ -0,0 +1,85 @@
+package selection
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/Zaphkiel-Ivanovna/GoogleAuthExtractor/internal/decoder"
+	"github.com/fatih/color"
+)
+
+var ErrPickerAborted = errors.New("account selection aborted")
+
+// Pick shows a checklist of the accounts on out and lets the user toggle
+// entries by number or range until they confirm with an empty line. All
+// accounts start checked.
+func Pick(accounts []decoder.Account, scanner *bufio.Scanner, out io.Writer) ([]decoder.Account, error) {
+	checked := make([]bool, len(accounts))
+	for i := range checked {
+		checked[i] = true
+	}
+
+	cyan := color.New(color.FgCyan, color.Bold).SprintFunc()
+	green := color.New(color.FgGreen).SprintFunc()
+
+	for {
+		fmt.Fprintf(out, "\n%s\n", cyan("Select accounts"))
+		for i, account := range accounts {
+			box := "[ ]"
+			if checked[i] {
+				box = green("[x]")
+			}
+			fmt.Fprintf(out, "  %s %2d. %s\n", box, i+1, Label(account))
+		}
+
+		fmt.Fprint(out, "\nToggle (e.g. 1 3 5-7), a=all, n=none, q=quit, Enter=done: ")
+		if !scanner.Scan() {
+			if err := scanner.Err(); err != nil {
+				return nil, fmt.Errorf("failed to read selection: %w", err)
+			}
+			return nil, ErrPickerAborted
+		}
+
+		switch answer := strings.ToLower(strings.TrimSpace(scanner.Text())); answer {
+		case "":
+			var selected []decoder.Account
+			for i, account := range accounts {
+				if checked[i] {
+					selected = append(selected, account)
+				}
+			}
+			return selected, nil
+		case "a", "all":
+			for i := range checked {
+				checked[i] = true
+			}
+		case "n", "none":
+			for i := range checked {
+				checked[i] = false
+			}
+		case "q", "quit":
+			return nil, ErrPickerAborted
+		default:
+			indexes, err := ParseIndexes(answer, len(accounts))
+			if err != nil {
+				fmt.Fprintf(out, "%s\n", color.YellowString("Warning: %v", err))
+				continue
+			}
+			for i := range indexes {
+				checked[i] = !checked[i]
+			}
+		}
+	}
+}
+
+// Label returns "Issuer (Name)", or just the name for accounts without an
+// issuer.
+func Label(account decoder.Account) string {
+	if account.Issuer == "" {
+		return account.Name
+	}
+	return fmt.Sprintf("%s (%s)", account.Issuer, account.Name)
+}
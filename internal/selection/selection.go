@@ -0,0 +1,196 @@
+package selection
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/Zaphkiel-Ivanovna/GoogleAuthExtractor/internal/decoder"
+)
+
+var ErrNoMatch = errors.New("no accounts match the selection")
+
+// Criteria selects a subset of the decoded accounts. Accounts must match
+// every criterion that is set, and at least one value of a criterion that
+// takes several.
+type Criteria struct {
+	// Issuers and Names are case-insensitive glob patterns ("git*"), or
+	// regular expressions when written between slashes ("/^ci-\d+$/").
+	Issuers []string
+	Names   []string
+	// Type is "totp" or "hotp".
+	Type string
+	// Algorithm is "sha1", "sha256", "sha512" or "md5".
+	Algorithm string
+	// Indexes lists 1-based positions in the decoded order, as
+	// comma-separated numbers and ranges: "1-3,5,8-".
+	Indexes string
+}
+
+func (c Criteria) IsZero() bool {
+	return len(c.Issuers) == 0 && len(c.Names) == 0 && c.Type == "" && c.Algorithm == "" && c.Indexes == ""
+}
+
+// Apply returns the accounts that match the criteria, in their original order.
+func Apply(accounts []decoder.Account, c Criteria) ([]decoder.Account, error) {
+	issuers, err := compilePatterns(c.Issuers)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --issuer: %w", err)
+	}
+
+	names, err := compilePatterns(c.Names)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --name: %w", err)
+	}
+
+	otpType := strings.ToUpper(c.Type)
+	if otpType != "" && otpType != "TOTP" && otpType != "HOTP" {
+		return nil, fmt.Errorf("invalid --type '%s': expected totp or hotp", c.Type)
+	}
+
+	algorithm := strings.ToUpper(c.Algorithm)
+	switch algorithm {
+	case "", "SHA1", "SHA256", "SHA512", "MD5":
+	default:
+		return nil, fmt.Errorf("invalid --algorithm '%s': expected sha1, sha256, sha512 or md5", c.Algorithm)
+	}
+
+	indexes, err := ParseIndexes(c.Indexes, len(accounts))
+	if err != nil {
+		return nil, err
+	}
+
+	var selected []decoder.Account
+	for i, account := range accounts {
+		if indexes != nil && !indexes[i] {
+			continue
+		}
+		if !matchAny(issuers, account.Issuer) || !matchAny(names, account.Name) {
+			continue
+		}
+		if otpType != "" && account.Type != otpType {
+			continue
+		}
+		if algorithm != "" && account.AlgorithmOrDefault() != algorithm {
+			continue
+		}
+		selected = append(selected, account)
+	}
+
+	return selected, nil
+}
+
+func matchAny(patterns []*Pattern, value string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, pattern := range patterns {
+		if pattern.Match(value) {
+			return true
+		}
+	}
+	return false
+}
+
+func compilePatterns(patterns []string) ([]*Pattern, error) {
+	compiled := make([]*Pattern, 0, len(patterns))
+	for _, pattern := range patterns {
+		p, err := ParsePattern(pattern)
+		if err != nil {
+			return nil, err
+		}
+		compiled = append(compiled, p)
+	}
+	return compiled, nil
+}
+
+// Pattern is a case-insensitive glob, or a regular expression when written
+// between slashes.
+type Pattern struct {
+	glob string
+	re   *regexp.Regexp
+}
+
+func ParsePattern(pattern string) (*Pattern, error) {
+	if len(pattern) >= 2 && strings.HasPrefix(pattern, "/") && strings.HasSuffix(pattern, "/") {
+		re, err := regexp.Compile("(?i)" + pattern[1:len(pattern)-1])
+		if err != nil {
+			return nil, fmt.Errorf("bad regular expression '%s': %w", pattern, err)
+		}
+		return &Pattern{re: re}, nil
+	}
+
+	glob := strings.ToLower(pattern)
+	if _, err := path.Match(glob, ""); err != nil {
+		return nil, fmt.Errorf("bad glob pattern '%s': %w", pattern, err)
+	}
+	return &Pattern{glob: glob}, nil
+}
+
+func (p *Pattern) Match(value string) bool {
+	if p.re != nil {
+		return p.re.MatchString(value)
+	}
+	ok, _ := path.Match(p.glob, strings.ToLower(value))
+	return ok
+}
+
+// Expand returns template with $1, ${name}... replaced by the submatches of
+// a regular expression in value. Globs have no submatches, so template is
+// returned as is.
+func (p *Pattern) Expand(template, value string) string {
+	if p.re == nil {
+		return template
+	}
+	match := p.re.FindStringSubmatchIndex(value)
+	if match == nil {
+		return template
+	}
+	return string(p.re.ExpandString(nil, template, value, match))
+}
+
+// ParseIndexes parses a list of 1-based positions and ranges such as
+// "1-3,5,8-" into a set of 0-based indexes below count. An empty spec
+// selects nothing in particular and returns nil.
+func ParseIndexes(spec string, count int) (map[int]bool, error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		return nil, nil
+	}
+
+	selected := make(map[int]bool)
+	for _, part := range strings.FieldsFunc(spec, func(r rune) bool { return r == ',' || r == ' ' }) {
+		first, last, isRange := strings.Cut(part, "-")
+
+		start, end := 1, count
+		var err error
+		if first != "" {
+			if start, err = strconv.Atoi(first); err != nil {
+				return nil, fmt.Errorf("invalid index '%s' in '%s'", part, spec)
+			}
+		}
+		if !isRange {
+			end = start
+		} else if last != "" {
+			if end, err = strconv.Atoi(last); err != nil {
+				return nil, fmt.Errorf("invalid index '%s' in '%s'", part, spec)
+			}
+		}
+
+		if start < 1 || start > end {
+			return nil, fmt.Errorf("invalid index range '%s' in '%s'", part, spec)
+		}
+		if start > count {
+			return nil, fmt.Errorf("index %d is out of range: only %d accounts were decoded", start, count)
+		}
+
+		for i := start; i <= end && i <= count; i++ {
+			selected[i-1] = true
+		}
+	}
+
+	return selected, nil
+}
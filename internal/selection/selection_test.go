@@ -0,0 +1,170 @@
+package selection
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Zaphkiel-Ivanovna/GoogleAuthExtractor/internal/decoder"
+)
+
+var testAccounts = []decoder.Account{
+	{Name: "alice@example.com", Issuer: "GitHub", Type: "TOTP", Algorithm: "SHA1"},
+	{Name: "alice", Issuer: "GitLab", Type: "TOTP", Algorithm: "SHA256"},
+	{Name: "ci-42", Issuer: "Jenkins", Type: "HOTP", Algorithm: "SHA1"},
+	{Name: "bob@example.com", Issuer: "", Type: "TOTP", Algorithm: "SHA512"},
+}
+
+func names(accounts []decoder.Account) []string {
+	var result []string
+	for _, account := range accounts {
+		result = append(result, account.Name)
+	}
+	return result
+}
+
+func TestApply(t *testing.T) {
+	tests := []struct {
+		name     string
+		criteria Criteria
+		expected []string
+	}{
+		{
+			name:     "No criteria",
+			criteria: Criteria{},
+			expected: []string{"alice@example.com", "alice", "ci-42", "bob@example.com"},
+		},
+		{
+			name:     "Issuer glob is case-insensitive",
+			criteria: Criteria{Issuers: []string{"git*"}},
+			expected: []string{"alice@example.com", "alice"},
+		},
+		{
+			name:     "Name regex",
+			criteria: Criteria{Names: []string{`/^ci-\d+$/`}},
+			expected: []string{"ci-42"},
+		},
+		{
+			name:     "Several patterns match any",
+			criteria: Criteria{Names: []string{"bob*", "alice"}},
+			expected: []string{"alice", "bob@example.com"},
+		},
+		{
+			name:     "Type",
+			criteria: Criteria{Type: "hotp"},
+			expected: []string{"ci-42"},
+		},
+		{
+			name:     "Algorithm and type combined",
+			criteria: Criteria{Type: "totp", Algorithm: "sha1"},
+			expected: []string{"alice@example.com"},
+		},
+		{
+			name:     "Index ranges",
+			criteria: Criteria{Indexes: "1,3-"},
+			expected: []string{"alice@example.com", "ci-42", "bob@example.com"},
+		},
+		{
+			name:     "Index and issuer combined",
+			criteria: Criteria{Indexes: "2-4", Issuers: []string{"git*"}},
+			expected: []string{"alice"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			selected, err := Apply(testAccounts, tt.criteria)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got := names(selected); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestApplyUnspecifiedAlgorithm(t *testing.T) {
+	accounts := []decoder.Account{
+		{Name: "exported", Type: "TOTP", Algorithm: "ALGORITHM_UNSPECIFIED"},
+		{Name: "sha256", Type: "TOTP", Algorithm: "SHA256"},
+	}
+
+	selected, err := Apply(accounts, Criteria{Algorithm: "sha1"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got := names(selected); !reflect.DeepEqual(got, []string{"exported"}) {
+		t.Errorf("Expected an unspecified algorithm to match sha1, got %v", got)
+	}
+}
+
+func TestApplyErrors(t *testing.T) {
+	tests := []Criteria{
+		{Names: []string{"/(unclosed/"}},
+		{Issuers: []string{"[a-"}},
+		{Type: "steam"},
+		{Algorithm: "sha3"},
+		{Indexes: "0"},
+		{Indexes: "3-1"},
+		{Indexes: "5"},
+		{Indexes: "x"},
+	}
+
+	for _, criteria := range tests {
+		if _, err := Apply(testAccounts, criteria); err == nil {
+			t.Errorf("Expected error for %+v", criteria)
+		}
+	}
+}
+
+func TestPick(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected []string
+		err      error
+	}{
+		{
+			name:     "Confirm all",
+			input:    "\n",
+			expected: []string{"alice@example.com", "alice", "ci-42", "bob@example.com"},
+		},
+		{
+			name:     "Toggle off",
+			input:    "2-3\n\n",
+			expected: []string{"alice@example.com", "bob@example.com"},
+		},
+		{
+			name:     "None then toggle on, ignoring bad input",
+			input:    "n\n9\n4\n\n",
+			expected: []string{"bob@example.com"},
+		},
+		{
+			name:  "Quit",
+			input: "q\n",
+			err:   ErrPickerAborted,
+		},
+		{
+			name:  "End of input",
+			input: "1\n",
+			err:   ErrPickerAborted,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			selected, err := Pick(testAccounts, bufio.NewScanner(strings.NewReader(tt.input)), &out)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("Expected error %v, got %v", tt.err, err)
+			}
+			if got := names(selected); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,474 @@
+// Package server implements the local HTTP JSON API of the serve command.
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Zaphkiel-Ivanovna/GoogleAuthExtractor/internal/decoder"
+	"github.com/Zaphkiel-Ivanovna/GoogleAuthExtractor/internal/input"
+	"github.com/Zaphkiel-Ivanovna/GoogleAuthExtractor/internal/logging"
+	"github.com/Zaphkiel-Ivanovna/GoogleAuthExtractor/internal/otp"
+	"github.com/Zaphkiel-Ivanovna/GoogleAuthExtractor/internal/secmem"
+)
+
+// DefaultMaxBodySize is large enough for screenshots and scanned PDFs.
+const DefaultMaxBodySize = 10 << 20
+
+// DefaultMaxDecodes bounds the decodes running at once, as each one may
+// decode a large image or inflate PDF streams.
+const DefaultMaxDecodes = 2
+
+type Options struct {
+	// Token must be sent by clients as "Authorization: Bearer <token>".
+	Token string
+	// MaxBodySize limits request bodies, in bytes.
+	MaxBodySize int64
+	// MaxDecodes limits the decode requests handled at once; others wait.
+	MaxDecodes int
+	// LocalHostOnly rejects requests whose Host header is not a loopback
+	// name, so that web pages cannot reach the API through DNS rebinding.
+	LocalHostOnly bool
+	Decode        decoder.Options
+	// FingerprintKey makes the account IDs. Use a random key when the user
+	// has none, as IDs made with an empty key are plain hashes of the
+	// secrets.
+	FingerprintKey []byte
+}
+
+// Server answers API requests. Stored accounts are kept in memory only and
+// are identified by their fingerprint.
+type Server struct {
+	opts Options
+	now  func() time.Time
+	// decodes holds a token for each decode in progress.
+	decodes chan struct{}
+
+	mu       sync.RWMutex
+	accounts []decoder.Account
+}
+
+// New returns a server storing accounts, which must have their fingerprints
+// set with opts.FingerprintKey.
+func New(accounts []decoder.Account, opts Options) *Server {
+	if opts.MaxBodySize <= 0 {
+		opts.MaxBodySize = DefaultMaxBodySize
+	}
+	if opts.MaxDecodes <= 0 {
+		opts.MaxDecodes = DefaultMaxDecodes
+	}
+	return &Server{opts: opts, now: time.Now, decodes: make(chan struct{}, opts.MaxDecodes), accounts: slices.Clone(accounts)}
+}
+
+// endpoints lists the methods allowed on each path.
+var endpoints = map[string]string{
+	"/v1/decode":   "POST",
+	"/v1/accounts": "GET, DELETE",
+	"/v1/codes":    "GET",
+}
+
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /v1/decode", s.handleDecode)
+	mux.HandleFunc("GET /v1/accounts", s.handleAccounts)
+	mux.HandleFunc("DELETE /v1/accounts", s.handleClear)
+	mux.HandleFunc("GET /v1/codes", s.handleCodes)
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if allowed, ok := endpoints[r.URL.Path]; ok {
+			w.Header().Set("Allow", allowed)
+			writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "method not allowed")
+			return
+		}
+		writeError(w, http.StatusNotFound, "not_found", "no such endpoint")
+	})
+	return s.logRequests(s.checkHost(s.authenticate(mux)))
+}
+
+// Serve answers requests on l until ctx is done, then waits for the
+// requests in progress to finish.
+func (s *Server) Serve(ctx context.Context, l net.Listener) error {
+	srv := &http.Server{
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: 5 * time.Second,
+		ReadTimeout:       30 * time.Second,
+		WriteTimeout:      30 * time.Second,
+		IdleTimeout:       time.Minute,
+		MaxHeaderBytes:    16 << 10,
+		// Handler panics recovered by net/http are reported here.
+		ErrorLog: slog.NewLogLogger(logging.Logger().Handler(), slog.LevelWarn),
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		done <- srv.Shutdown(shutdownCtx)
+	}()
+
+	if err := srv.Serve(l); !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("failed to serve: %w", err)
+	}
+	return <-done
+}
+
+// Close wipes the secrets of the stored accounts.
+func (s *Server) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	destroy(s.accounts)
+	s.accounts = nil
+}
+
+// logRequests logs the method, path and status of requests. Bodies, query
+// strings and headers are never logged, as they carry secrets and the token.
+func (s *Server) logRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := s.now()
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+		logging.Debugf("%s %s %d %s", r.Method, r.URL.Path, rec.status, s.now().Sub(start).Round(time.Millisecond))
+	})
+}
+
+func (s *Server) checkHost(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.opts.LocalHostOnly && !IsLoopbackHost(r.Host) {
+			writeError(w, http.StatusForbidden, "forbidden_host", "only requests to localhost are accepted")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *Server) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || s.opts.Token == "" || subtle.ConstantTimeCompare([]byte(token), []byte(s.opts.Token)) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeError(w, http.StatusUnauthorized, "unauthorized", "missing or invalid token")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// IsLoopbackHost reports whether host, with or without a port, is localhost
+// or a loopback address.
+func IsLoopbackHost(host string) bool {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.Trim(host, "[]")
+	if strings.EqualFold(host, "localhost") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+type decodeRequest struct {
+	URIs  []string `json:"uris"`
+	URI   string   `json:"uri"`
+	Store bool     `json:"store"`
+}
+
+type decodeResponse struct {
+	Accounts []decoder.Account `json:"accounts"`
+	Stored   int               `json:"stored"`
+}
+
+// handleDecode decodes a JSON request holding migration URIs, or an image
+// or PDF sent as the body, and returns the accounts with their secrets.
+func (s *Server) handleDecode(w http.ResponseWriter, r *http.Request) {
+	// Bodies are read once a slot is free, so waiting requests hold no
+	// memory either.
+	select {
+	case s.decodes <- struct{}{}:
+		defer func() { <-s.decodes }()
+	case <-r.Context().Done():
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, s.opts.MaxBodySize))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeError(w, http.StatusRequestEntityTooLarge, "too_large", fmt.Sprintf("request body exceeds %d bytes", s.opts.MaxBodySize))
+			return
+		}
+		writeError(w, http.StatusBadRequest, "invalid_request", "failed to read request body")
+		return
+	}
+	defer secmem.Wipe(body)
+
+	var req decodeRequest
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType == "application/json" {
+		dec := json.NewDecoder(bytes.NewReader(body))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid_request", "request body is not a valid decode request")
+			return
+		}
+	} else {
+		results, err := input.ScanQRCodes(body)
+		if err != nil {
+			writeDecodeError(w, err)
+			return
+		}
+		for _, result := range results {
+			req.URIs = append(req.URIs, result.Text)
+		}
+		req.Store = r.URL.Query().Get("store") == "true"
+	}
+
+	if req.URI != "" {
+		req.URIs = append(req.URIs, req.URI)
+	}
+	if len(req.URIs) == 0 {
+		writeError(w, http.StatusBadRequest, "invalid_request", "no URIs given")
+		return
+	}
+
+	accounts, err := decoder.DecodeExportURIs(req.URIs, s.opts.Decode)
+	if err == nil {
+		err = decoder.SetFingerprints(accounts, s.opts.FingerprintKey)
+	}
+	if err != nil {
+		destroy(accounts)
+		writeDecodeError(w, err)
+		return
+	}
+
+	// Accounts are stored once the response is written, as a concurrent
+	// DELETE /v1/accounts would wipe their secrets.
+	resp := decodeResponse{Accounts: accounts}
+	if req.Store {
+		resp.Stored = s.count(accounts)
+	}
+	writeJSON(w, http.StatusOK, resp)
+
+	if req.Store {
+		s.store(accounts)
+	} else {
+		destroy(accounts)
+	}
+}
+
+// count returns the number of accounts that are not stored yet.
+func (s *Server) count(accounts []decoder.Account) int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	seen := make(map[string]bool)
+	for _, account := range s.accounts {
+		seen[account.Fingerprint] = true
+	}
+	added := 0
+	for _, account := range accounts {
+		if !seen[account.Fingerprint] {
+			seen[account.Fingerprint] = true
+			added++
+		}
+	}
+	return added
+}
+
+// store adds the accounts that are not stored yet. Accounts stored already
+// are replaced, keeping the latest HOTP counter.
+func (s *Server) store(accounts []decoder.Account) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, account := range accounts {
+		i := slices.IndexFunc(s.accounts, func(stored decoder.Account) bool {
+			return stored.Fingerprint == account.Fingerprint
+		})
+		if i < 0 {
+			s.accounts = append(s.accounts, account)
+			continue
+		}
+		s.accounts[i].Destroy()
+		s.accounts[i] = account
+	}
+}
+
+// accountInfo describes a stored account without its secret.
+type accountInfo struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	Issuer    string `json:"issuer,omitempty"`
+	Type      string `json:"type"`
+	Algorithm string `json:"algorithm"`
+	Digits    int    `json:"digits"`
+	Counter   *int64 `json:"counter,omitempty"`
+}
+
+func describe(account decoder.Account) accountInfo {
+	info := accountInfo{
+		ID:        account.Fingerprint,
+		Name:      account.Name,
+		Issuer:    account.Issuer,
+		Type:      account.Type,
+		Algorithm: account.AlgorithmOrDefault(),
+		Digits:    account.DigitCount(),
+	}
+	if account.Type == "HOTP" {
+		counter := account.Counter
+		info.Counter = &counter
+	}
+	return info
+}
+
+func (s *Server) handleAccounts(w http.ResponseWriter, r *http.Request) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	infos := make([]accountInfo, len(s.accounts))
+	for i, account := range s.accounts {
+		infos[i] = describe(account)
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"accounts": infos})
+}
+
+func (s *Server) handleClear(w http.ResponseWriter, r *http.Request) {
+	s.Close()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+type codeInfo struct {
+	accountInfo
+	Code string `json:"code,omitempty"`
+	// ExpiresIn is the number of seconds a TOTP code stays valid.
+	ExpiresIn int `json:"expiresIn,omitempty"`
+	// Error explains why no code could be generated, e.g. for an
+	// unsupported algorithm.
+	Error string `json:"error,omitempty"`
+}
+
+// handleCodes returns the current codes of the stored accounts, or of the
+// accounts given with the id parameter. HOTP codes are those of the stored
+// counter, which is not advanced.
+func (s *Server) handleCodes(w http.ResponseWriter, r *http.Request) {
+	ids := r.URL.Query()["id"]
+	now := s.now()
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	codes := []codeInfo{}
+	for _, account := range s.accounts {
+		if len(ids) > 0 && !slices.Contains(ids, account.Fingerprint) {
+			continue
+		}
+
+		code, err := generateCode(account, now)
+		if err != nil {
+			// One unusable account should not hide the codes of the others.
+			code = codeInfo{accountInfo: describe(account), Error: logging.Redact(err.Error())}
+		}
+		codes = append(codes, code)
+	}
+
+	if len(ids) > 0 && len(codes) == 0 {
+		writeError(w, http.StatusNotFound, "not_found", "no stored account has this id")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"codes": codes})
+}
+
+func generateCode(account decoder.Account, now time.Time) (codeInfo, error) {
+	info := codeInfo{accountInfo: describe(account)}
+
+	secret, err := account.SecretBytes()
+	if err != nil {
+		return info, err
+	}
+
+	if account.Type == "HOTP" {
+		info.Code, err = otp.HOTP(secret, uint64(account.Counter), info.Digits, info.Algorithm)
+		return info, err
+	}
+
+	info.Code, err = otp.TOTP(secret, now, info.Digits, info.Algorithm)
+	step := int64(otp.Period / time.Second)
+	info.ExpiresIn = int(step - now.Unix()%step)
+	return info, err
+}
+
+func destroy(accounts []decoder.Account) {
+	for _, account := range accounts {
+		account.Destroy()
+	}
+}
+
+type errorResponse struct {
+	Error struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+func writeError(w http.ResponseWriter, status int, code, message string) {
+	var resp errorResponse
+	resp.Error.Code, resp.Error.Message = code, message
+	writeJSON(w, status, resp)
+}
+
+var decodeErrors = []struct {
+	err    error
+	code   string
+	status int
+}{
+	{decoder.ErrInvalidScheme, "invalid_scheme", http.StatusUnprocessableEntity},
+	{decoder.ErrInvalidURI, "invalid_uri", http.StatusUnprocessableEntity},
+	{decoder.ErrMissingData, "missing_data", http.StatusUnprocessableEntity},
+	{decoder.ErrInvalidBase64, "invalid_base64", http.StatusUnprocessableEntity},
+	{decoder.ErrInvalidProtobuf, "invalid_protobuf", http.StatusUnprocessableEntity},
+	{decoder.ErrUnsupportedVersion, "unsupported_version", http.StatusUnprocessableEntity},
+	{input.ErrNoQRCode, "no_qr_code", http.StatusUnprocessableEntity},
+	{input.ErrUnsupportedFormat, "unsupported_format", http.StatusUnsupportedMediaType},
+	{input.ErrImageTooLarge, "image_too_large", http.StatusRequestEntityTooLarge},
+}
+
+// writeDecodeError reports a decoding failure with the codes of the CLI's
+// --error-format json. Messages are redacted, since they may quote the URI.
+func writeDecodeError(w http.ResponseWriter, err error) {
+	for _, class := range decodeErrors {
+		if errors.Is(err, class.err) {
+			writeError(w, class.status, class.code, logging.Redact(err.Error()))
+			return
+		}
+	}
+	writeError(w, http.StatusUnprocessableEntity, "error", logging.Redact(err.Error()))
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
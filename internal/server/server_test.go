@@ -0,0 +1,343 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"hash/crc32"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Zaphkiel-Ivanovna/GoogleAuthExtractor/internal/decoder"
+	"github.com/Zaphkiel-Ivanovna/GoogleAuthExtractor/internal/otp"
+	"github.com/skip2/go-qrcode"
+)
+
+const (
+	testToken = "test-token"
+	// Two TOTP accounts and an HOTP account with counter 1.
+	testURI = "otpauth-migration://offline?data=CiIKCkhlbGwPId6tvugSDlRlc3QgYWNjb3VudCAxIAEoATACCiIKCgBlbGxvId6tvu8SDlRlc3QgYWNjb3VudCAyIAEoATACCiMKCgBEjWxkLzvjHR8SDUNvdW50ZXIga2V5IDEgASgBMAE4ARABGAEgACj8nJf4Bg%3D%3D"
+)
+
+func request(t *testing.T, h http.Handler, method, target, contentType string, body []byte) *httptest.ResponseRecorder {
+	t.Helper()
+
+	req := httptest.NewRequest(method, target, bytes.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+testToken)
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func errorCode(t *testing.T, rec *httptest.ResponseRecorder) string {
+	t.Helper()
+
+	var resp errorResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Failed to parse error response %q: %v", rec.Body.String(), err)
+	}
+	return resp.Error.Code
+}
+
+func TestAuthentication(t *testing.T) {
+	h := New(nil, Options{Token: testToken, LocalHostOnly: true}).Handler()
+
+	tests := []struct {
+		name     string
+		header   string
+		host     string
+		expected int
+	}{
+		{"Valid token", "Bearer " + testToken, "localhost:8765", http.StatusOK},
+		{"Loopback address", "Bearer " + testToken, "127.0.0.1:8765", http.StatusOK},
+		{"Missing token", "", "localhost:8765", http.StatusUnauthorized},
+		{"Wrong token", "Bearer wrong", "localhost:8765", http.StatusUnauthorized},
+		{"Basic scheme", "Basic " + testToken, "localhost:8765", http.StatusUnauthorized},
+		{"Foreign host", "Bearer " + testToken, "attacker.example:8765", http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/v1/accounts", nil)
+			req.Host = tt.host
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			if rec.Code != tt.expected {
+				t.Errorf("Expected status %d, got %d", tt.expected, rec.Code)
+			}
+		})
+	}
+}
+
+func TestDecode(t *testing.T) {
+	png, err := qrcode.Encode(testURI, qrcode.Medium, 512)
+	if err != nil {
+		t.Fatalf("Failed to generate QR code: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		contentType string
+		body        []byte
+		status      int
+		code        string
+		accounts    int
+	}{
+		{"URI list", "application/json", []byte(`{"uris":["` + testURI + `"]}`), http.StatusOK, "", 3},
+		{"Single URI", "application/json; charset=utf-8", []byte(`{"uri":"` + testURI + `"}`), http.StatusOK, "", 3},
+		{"Image", "image/png", png, http.StatusOK, "", 3},
+		{"No URI", "application/json", []byte(`{}`), http.StatusBadRequest, "invalid_request", 0},
+		{"Unknown field", "application/json", []byte(`{"url":"x"}`), http.StatusBadRequest, "invalid_request", 0},
+		{"Invalid base64", "application/json", []byte(`{"uri":"otpauth-migration://offline?data=not-base64"}`), http.StatusUnprocessableEntity, "invalid_base64", 0},
+		{"Not an image", "application/octet-stream", []byte("hello"), http.StatusUnsupportedMediaType, "unsupported_format", 0},
+		{"Too large", "application/json", bytes.Repeat([]byte(" "), 2048), http.StatusRequestEntityTooLarge, "too_large", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := New(nil, Options{Token: testToken, MaxBodySize: 1024 * 1024}).Handler()
+			if tt.name == "Too large" {
+				h = New(nil, Options{Token: testToken, MaxBodySize: 1024}).Handler()
+			}
+
+			rec := request(t, h, http.MethodPost, "/v1/decode", tt.contentType, tt.body)
+			if rec.Code != tt.status {
+				t.Fatalf("Expected status %d, got %d: %s", tt.status, rec.Code, rec.Body.String())
+			}
+			if tt.code != "" {
+				if code := errorCode(t, rec); code != tt.code {
+					t.Errorf("Expected error code %s, got %s", tt.code, code)
+				}
+				return
+			}
+
+			var resp struct {
+				Accounts []decoder.Account `json:"accounts"`
+			}
+			if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("Failed to parse response: %v", err)
+			}
+			if len(resp.Accounts) != tt.accounts {
+				t.Fatalf("Expected %d accounts, got %d", tt.accounts, len(resp.Accounts))
+			}
+			if resp.Accounts[0].TOTPSecret == "" || resp.Accounts[0].Fingerprint == "" {
+				t.Errorf("Expected the secret and fingerprint of decoded accounts, got %+v", resp.Accounts[0])
+			}
+		})
+	}
+}
+
+func TestDecodeImageTooLarge(t *testing.T) {
+	h := New(nil, Options{Token: testToken}).Handler()
+
+	// A PNG declaring 100000x100000 pixels, which would take 10 GB decoded.
+	var body bytes.Buffer
+	body.WriteString("\x89PNG\r\n\x1a\n")
+	for _, chunk := range []struct {
+		kind string
+		data []byte
+	}{
+		{"IHDR", []byte{0, 1, 0x86, 0xa0, 0, 1, 0x86, 0xa0, 8, 0, 0, 0, 0}},
+		{"IDAT", nil},
+		{"IEND", nil},
+	} {
+		binary.Write(&body, binary.BigEndian, uint32(len(chunk.data)))
+		crc := crc32.NewIEEE()
+		crc.Write([]byte(chunk.kind))
+		crc.Write(chunk.data)
+		body.WriteString(chunk.kind)
+		body.Write(chunk.data)
+		binary.Write(&body, binary.BigEndian, crc.Sum32())
+	}
+
+	rec := request(t, h, http.MethodPost, "/v1/decode", "image/png", body.Bytes())
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusRequestEntityTooLarge, rec.Code, rec.Body.String())
+	}
+	if code := errorCode(t, rec); code != "image_too_large" {
+		t.Errorf("Expected error code image_too_large, got %s", code)
+	}
+}
+
+func TestDecodeConcurrency(t *testing.T) {
+	srv := New(nil, Options{Token: testToken, MaxDecodes: 1})
+	h := srv.Handler()
+	body := []byte(`{"uri":"` + testURI + `"}`)
+
+	srv.decodes <- struct{}{}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	req := httptest.NewRequest(http.MethodPost, "/v1/decode", bytes.NewReader(body)).WithContext(ctx)
+	req.Header.Set("Authorization", "Bearer "+testToken)
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if strings.Contains(rec.Body.String(), "accounts") {
+		t.Errorf("Expected the request to wait for a free slot, got %s", rec.Body.String())
+	}
+
+	<-srv.decodes
+	if rec := request(t, h, http.MethodPost, "/v1/decode", "application/json", body); rec.Code != http.StatusOK {
+		t.Errorf("Expected status %d once a slot is free, got %d", http.StatusOK, rec.Code)
+	}
+}
+
+func TestDecodeErrorsAreRedacted(t *testing.T) {
+	h := New(nil, Options{Token: testToken}).Handler()
+
+	uri := "otpauth-migration://offline?data=" + strings.Repeat("QUJD", 8)
+	rec := request(t, h, http.MethodPost, "/v1/decode", "application/json", []byte(`{"uri":"`+uri+`"}`))
+	if strings.Contains(rec.Body.String(), "QUJD") {
+		t.Errorf("Expected the URI to be redacted, got %s", rec.Body.String())
+	}
+}
+
+func TestStoredCodes(t *testing.T) {
+	srv := New(nil, Options{Token: testToken})
+	srv.now = func() time.Time { return time.Unix(1700000020, 0) }
+	h := srv.Handler()
+
+	body := []byte(`{"uri":"` + testURI + `","store":true}`)
+	for i, expected := range []string{`"stored":3`, `"stored":0`} {
+		rec := request(t, h, http.MethodPost, "/v1/decode", "application/json", body)
+		if !strings.Contains(rec.Body.String(), expected) {
+			t.Errorf("Expected %s after request %d, got %s", expected, i+1, rec.Body.String())
+		}
+	}
+
+	rec := request(t, h, http.MethodGet, "/v1/accounts", "", nil)
+	if strings.Contains(rec.Body.String(), "ecret") {
+		t.Errorf("Expected no secrets in the account list, got %s", rec.Body.String())
+	}
+	var list struct {
+		Accounts []accountInfo `json:"accounts"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &list); err != nil || len(list.Accounts) != 3 {
+		t.Fatalf("Expected 3 stored accounts, got %s (%v)", rec.Body.String(), err)
+	}
+
+	accounts, err := decoder.DecodeExportURI(testURI)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	totpSecret, _ := accounts[0].SecretBytes()
+	totp, _ := otp.TOTP(totpSecret, srv.now(), 6, "SHA1")
+	hotpSecret, _ := accounts[2].SecretBytes()
+	hotp, _ := otp.HOTP(hotpSecret, 1, 6, "SHA1")
+
+	rec = request(t, h, http.MethodGet, "/v1/codes", "", nil)
+	var resp struct {
+		Codes []codeInfo `json:"codes"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil || len(resp.Codes) != 3 {
+		t.Fatalf("Expected 3 codes, got %s (%v)", rec.Body.String(), err)
+	}
+	if resp.Codes[0].Code != totp || resp.Codes[0].ExpiresIn != 20 {
+		t.Errorf("Expected TOTP code %s valid for 20s, got %s valid for %ds", totp, resp.Codes[0].Code, resp.Codes[0].ExpiresIn)
+	}
+	if resp.Codes[2].Code != hotp {
+		t.Errorf("Expected HOTP code %s, got %s", hotp, resp.Codes[2].Code)
+	}
+
+	rec = request(t, h, http.MethodGet, "/v1/codes?id="+list.Accounts[1].ID, "", nil)
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil || len(resp.Codes) != 1 || resp.Codes[0].Name != "Test account 2" {
+		t.Errorf("Expected the code of Test account 2, got %s", rec.Body.String())
+	}
+
+	rec = request(t, h, http.MethodGet, "/v1/codes?id=AAAA-AAAA-AAAA-AAAA", "", nil)
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Expected status %d for an unknown id, got %d", http.StatusNotFound, rec.Code)
+	}
+
+	request(t, h, http.MethodDelete, "/v1/accounts", "", nil)
+	rec = request(t, h, http.MethodGet, "/v1/codes", "", nil)
+	if !strings.Contains(rec.Body.String(), `"codes":[]`) {
+		t.Errorf("Expected no codes after clearing the store, got %s", rec.Body.String())
+	}
+}
+
+func TestCodesWithUnsupportedAlgorithm(t *testing.T) {
+	accounts := []decoder.Account{
+		{Name: "md5", Secret: "SGVsbG8h3q2+7w==", TOTPSecret: "JBSWY3DPEHPK3PXP", Type: "TOTP", Algorithm: "MD5"},
+		{Name: "sha1", Secret: "SGVsbG8h3q2+7w==", TOTPSecret: "JBSWY3DPEHPK3PXP", Type: "TOTP", Algorithm: "SHA1"},
+	}
+	if err := decoder.SetFingerprints(accounts, []byte("key")); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	h := New(accounts, Options{Token: testToken}).Handler()
+
+	rec := request(t, h, http.MethodGet, "/v1/codes", "", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	var resp struct {
+		Codes []codeInfo `json:"codes"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil || len(resp.Codes) != 2 {
+		t.Fatalf("Expected 2 codes, got %s (%v)", rec.Body.String(), err)
+	}
+	if resp.Codes[0].Code != "" || resp.Codes[0].Error == "" {
+		t.Errorf("Expected an error for the MD5 account, got %+v", resp.Codes[0])
+	}
+	if resp.Codes[1].Code == "" || resp.Codes[1].Error != "" {
+		t.Errorf("Expected a code for the SHA1 account, got %+v", resp.Codes[1])
+	}
+}
+
+func TestUnknownRoutes(t *testing.T) {
+	h := New(nil, Options{Token: testToken}).Handler()
+
+	tests := []struct {
+		method   string
+		target   string
+		expected int
+	}{
+		{http.MethodPut, "/v1/codes", http.StatusMethodNotAllowed},
+		{http.MethodGet, "/v1/decode", http.StatusMethodNotAllowed},
+		{http.MethodGet, "/v2/codes", http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.target, func(t *testing.T) {
+			rec := request(t, h, tt.method, tt.target, "", nil)
+			if rec.Code != tt.expected {
+				t.Errorf("Expected status %d, got %d", tt.expected, rec.Code)
+			}
+		})
+	}
+}
+
+func TestIsLoopbackHost(t *testing.T) {
+	tests := []struct {
+		host     string
+		expected bool
+	}{
+		{"localhost", true},
+		{"LOCALHOST:80", true},
+		{"127.0.0.1:8765", true},
+		{"[::1]:8765", true},
+		{"::1", true},
+		{"192.168.1.10:8765", false},
+		{"localhost.attacker.example", false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.host, func(t *testing.T) {
+			if got := IsLoopbackHost(tt.host); got != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}